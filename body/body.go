@@ -10,6 +10,7 @@ import (
 
 	"github.com/chronos-tachyon/assert"
 	"github.com/chronos-tachyon/bufferpool"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/encoding/prototext"
 	"google.golang.org/protobuf/proto"
 )
@@ -66,7 +67,9 @@ type Body interface {
 	// always have independent cursors.
 	//
 	// Note: this operation can be very cheap or very expensive, depending
-	// on the implementation.
+	// on the implementation.  For example, copying a Body created by Pipe
+	// or FromFunc makes it buffer its remaining bytes, so that it no
+	// longer streams them as they are produced.
 	//
 	// If this Body implements any advanced I/O interfaces, then so will
 	// the returned copy.  As the two instances have independent cursors,
@@ -202,6 +205,7 @@ func fromJSONImpl(v interface{}, isPretty bool) Body {
 			}
 		}
 	} else {
+		dst = make([]byte, len(src))
 		copy(dst, src)
 	}
 
@@ -254,6 +258,32 @@ func FromProtoText(msg proto.Message, o *prototext.MarshalOptions) Body {
 	return FromBytes(raw)
 }
 
+// FromProtoJSON returns a new Body which serves a protobuf message in its
+// canonical JSON encoding.
+//
+// The MarshalOptions argument MAY be nil, in which case sensible defaults are
+// used.
+//
+// Current and future implementations make these promises:
+//
+// - The returned Body implementation will provide io.ReaderAt, io.Seeker, and
+//   io.WriterTo.
+//
+func FromProtoJSON(msg proto.Message, o *protojson.MarshalOptions) Body {
+	assert.NotNil(&msg)
+
+	if o == nil {
+		o = &protojson.MarshalOptions{}
+	}
+
+	raw, err := o.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+
+	return FromBytes(raw)
+}
+
 // FromReader returns a new Body which serves bytes from a Reader.
 //
 // If the provided Reader also implements io.Closer, then the call to
@@ -262,7 +292,8 @@ func FromProtoText(msg proto.Message, o *prototext.MarshalOptions) Body {
 //
 // The provided implementation of Body may take advantage of more advanced
 // interfaces provided by the Reader's concrete implementation, such as
-// fs.File, io.ReaderAt, or io.Seeker.
+// fs.File, io.ReaderAt, or io.Seeker.  Readers which provide neither
+// io.ReaderAt nor io.Seeker are buffered as configured by DefaultBufferOptions.
 //
 // Current and future implementations make these promises:
 //
@@ -274,6 +305,9 @@ func FromProtoText(msg proto.Message, o *prototext.MarshalOptions) Body {
 //   implementation of Body will support io.Seeker and io.ReaderAt, the latter
 //   through emulation using io.ReadSeeker.
 //
+// - Otherwise, the returned implementation of Body will support io.WriterTo,
+//   plus io.ReaderAt and io.Seeker if BufferOptions.Retain is set.
+//
 func FromReader(r io.Reader) (Body, error) {
 	assert.NotNil(&r)
 
@@ -289,8 +323,24 @@ func FromReader(r io.Reader) (Body, error) {
 // See FromReader for details.
 //
 func FromReaderAndLength(r io.Reader, length int64) (Body, error) {
+	return FromReaderWithOptions(r, length, nil)
+}
+
+// FromReaderWithOptions returns a new Body which serves bytes from a Reader,
+// using the given BufferOptions if the Reader must be buffered.
+//
+// The BufferOptions argument MAY be nil, in which case DefaultBufferOptions is
+// used.
+//
+// See FromReader for details.
+//
+func FromReaderWithOptions(r io.Reader, length int64, opts *BufferOptions) (Body, error) {
 	assert.NotNil(&r)
 
+	if opts == nil {
+		opts = &DefaultBufferOptions
+	}
+
 	if length < 0 {
 		length = -1
 	}
@@ -348,8 +398,124 @@ func FromReaderAndLength(r io.Reader, length int64) (Body, error) {
 		r:      r,
 		bodies: make(map[*bufferedBody]struct{}, 4),
 		length: length,
+		opts:   *opts,
+		budget: opts.Budget,
+	}
+	if common.budget == nil {
+		common.budget = DefaultBudget
 	}
 	body := &bufferedBody{common: common}
 	common.ref(body)
+	if opts.Retain {
+		return &bufferedAtBody{body}, nil
+	}
 	return body, nil
 }
+
+// Concat returns a new Body which serves the bytes of each of the given
+// Bodies, one after another.  The returned Body takes ownership of the parts.
+//
+// The length of the returned Body is the sum of the lengths of the parts, or
+// -1 if the length of any part is unknown.
+//
+// Calling Close() on the returned Body closes every part which has not
+// already been closed, and returns all of the resulting errors as a
+// MultiError.
+//
+// Current and future implementations make these promises:
+//
+// - The returned Body implementation will provide io.WriterTo.
+//
+// - If every part has a known length and provides io.Seeker, then the returned
+//   Body implementation will provide io.Seeker.
+//
+// - If every part has a known length and provides io.ReaderAt, then the
+//   returned Body implementation will provide io.ReaderAt.  Offsets are
+//   interpreted relative to each part's cursor at the time of the call to
+//   Concat, if the part provides io.Seeker, or relative to the start of the
+//   part otherwise.
+//
+// - If neither io.Seeker nor io.ReaderAt are provided, then each part is
+//   closed as soon as it has been read to EOF.
+//
+func Concat(parts ...Body) Body {
+	for index := range parts {
+		assert.NotNil(&parts[index])
+	}
+
+	switch len(parts) {
+	case 0:
+		return Empty()
+	case 1:
+		return parts[0]
+	}
+
+	list := make([]Body, len(parts))
+	copy(list, parts)
+
+	bases := make([]int64, len(list))
+	starts := make([]int64, len(list)+1)
+	canSeek := true
+	canReadAt := true
+	for index, part := range list {
+		length := part.BytesRemaining()
+		if length < 0 {
+			canSeek = false
+			canReadAt = false
+		}
+
+		if _, ok := part.(io.ReaderAt); !ok {
+			canReadAt = false
+		}
+
+		if s, ok := part.(io.Seeker); ok {
+			base, err := s.Seek(0, io.SeekCurrent)
+			if err != nil {
+				canSeek = false
+				canReadAt = false
+			}
+			bases[index] = base
+		} else {
+			canSeek = false
+		}
+
+		starts[index+1] = starts[index] + length
+	}
+
+	body := &concatBody{
+		parts:     list,
+		bases:     bases,
+		starts:    starts,
+		canSeek:   canSeek,
+		canReadAt: canReadAt,
+	}
+	return body.wrap()
+}
+
+// Slice returns a new Body which serves a sub-range of the bytes served by the
+// given Body, without copying them.
+//
+// The offset is interpreted in the same way as an offset passed to ReadAt:
+// that is, relative to the start of the underlying data, not relative to the
+// cursor of the given Body.  The range [offset, offset+length) MUST lie within
+// the underlying data, or else a SliceOutOfRangeError is returned.
+//
+// The given Body remains owned by the caller and its cursor is not affected.
+// The returned Body is independent of it, with its own cursor, but it shares
+// the underlying data using the same reference counting as Copy().  As such,
+// both the given Body and the returned Body must be closed.
+//
+// Slicing is supported for Bodies created by FromBytes, FromString, FromJSON,
+// FromProto, FromProtoText, Slice, and FromReader with a Reader that supports
+// io.ReaderAt or io.Seeker, plus any other Body of known length which
+// provides io.ReaderAt.  Other Bodies return a NotSliceableError.
+//
+// Current and future implementations make these promises:
+//
+// - The returned Body implementation will provide io.ReaderAt, io.Seeker, and
+//   io.WriterTo.
+//
+func Slice(b Body, offset, length int64) (Body, error) {
+	assert.NotNil(&b)
+	return sliceImpl(b, offset, length)
+}
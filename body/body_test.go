@@ -2,6 +2,8 @@ package body
 
 import (
 	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestFromPrettyJSON(t *testing.T) {
@@ -15,3 +17,24 @@ func TestFromPrettyJSON(t *testing.T) {
 		t.Errorf("expected %q, got %q", expect, actual)
 	}
 }
+
+func TestFromJSON(t *testing.T) {
+	arr := []int{1, 2, 3}
+	b := FromJSON(arr)
+
+	expect := "[1,2,3]\n"
+	actual := string(b.(*bytesBody).data)
+	if expect != actual {
+		t.Errorf("expected %q, got %q", expect, actual)
+	}
+}
+
+func TestFromProtoJSON(t *testing.T) {
+	b := FromProtoJSON(wrapperspb.String("abc"), nil)
+
+	expect := `"abc"`
+	actual := string(b.(*bytesBody).data)
+	if expect != actual {
+		t.Errorf("expected %q, got %q", expect, actual)
+	}
+}
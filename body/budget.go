@@ -0,0 +1,96 @@
+package body
+
+import (
+	"sync"
+)
+
+// Budget limits and accounts for the memory which buffered Bodies hold.
+//
+// Every Body which buffers a Reader, i.e. one created by FromReader for a
+// Reader which provides neither io.ReaderAt nor io.Seeker, is charged against
+// a Budget for the memory that it allocates to buffer bytes.  Bytes which have
+// been spilled to disk are not charged.
+//
+type Budget struct {
+	mu     sync.Mutex
+	limit  int64
+	used   int64
+	bodies int64
+}
+
+// NewBudget constructs a new Budget with the given limit, in bytes.  If the
+// limit is zero or negative, the Budget only keeps accounts.
+func NewBudget(limit int64) *Budget {
+	if limit < 0 {
+		limit = 0
+	}
+	return &Budget{limit: limit}
+}
+
+// DefaultBudget is the Budget used when BufferOptions.Budget is nil.  It has
+// no limit.
+var DefaultBudget = NewBudget(0)
+
+// Limit returns the maximum number of bytes which may be buffered in memory,
+// or 0 if there is no limit.
+func (budget *Budget) Limit() int64 {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	return budget.limit
+}
+
+// SetLimit changes the limit.  Lowering the limit below the number of bytes
+// already in use does not affect existing buffers, but prevents them from
+// growing.
+func (budget *Budget) SetLimit(limit int64) {
+	if limit < 0 {
+		limit = 0
+	}
+
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	budget.limit = limit
+}
+
+// Used returns the number of bytes currently buffered in memory.
+func (budget *Budget) Used() int64 {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	return budget.used
+}
+
+// Bodies returns the number of live Bodies charged to this Budget.
+func (budget *Budget) Bodies() int64 {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	return budget.bodies
+}
+
+func (budget *Budget) acquire(n int64) error {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	if budget.limit > 0 && budget.used+n > budget.limit {
+		return BufferBudgetExceededError{Requested: n, Used: budget.used, Limit: budget.limit}
+	}
+	budget.used += n
+	return nil
+}
+
+func (budget *Budget) release(n int64) {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	budget.used -= n
+}
+
+func (budget *Budget) addBody(delta int64) {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	budget.bodies += delta
+}
@@ -0,0 +1,143 @@
+package body
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestBudget_Exceeded(t *testing.T) {
+	budget := NewBudget(2 * blockSize)
+
+	data := make([]byte, 4*blockSize)
+	b1, err := FromReaderWithOptions(io.MultiReader(bytes.NewReader(data)), -1, &BufferOptions{Budget: budget})
+	if err != nil {
+		t.Fatalf("FromReaderWithOptions failed: %v", err)
+	}
+
+	b2, err := b1.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	if expect, actual := int64(2), budget.Bodies(); expect != actual {
+		t.Errorf("Bodies: expected %d, got %d", expect, actual)
+	}
+
+	_, err = io.ReadAll(b1)
+	if !errors.Is(err, ErrBufferBudgetExceeded) {
+		t.Errorf("ReadAll: expected ErrBufferBudgetExceeded, got %s", formatAny(err))
+	}
+
+	var xerr BufferBudgetExceededError
+	if !errors.As(err, &xerr) {
+		t.Errorf("ReadAll: expected BufferBudgetExceededError, got %s", formatAny(err))
+	} else if expect := int64(2 * blockSize); xerr.Limit != expect {
+		t.Errorf("BufferBudgetExceededError.Limit: expected %d, got %d", expect, xerr.Limit)
+	}
+
+	if actual := budget.Used(); actual <= 0 || actual > budget.Limit() {
+		t.Errorf("Used: expected between 1 and %d, got %d", budget.Limit(), actual)
+	}
+
+	_ = b1.Close()
+	_ = b2.Close()
+
+	if expect, actual := int64(0), budget.Used(); expect != actual {
+		t.Errorf("Used after Close: expected %d, got %d", expect, actual)
+	}
+	if expect, actual := int64(0), budget.Bodies(); expect != actual {
+		t.Errorf("Bodies after Close: expected %d, got %d", expect, actual)
+	}
+}
+
+func TestBudget_RetryAfterRelease(t *testing.T) {
+	budget := NewBudget(blockSize)
+	opts := &BufferOptions{Budget: budget}
+
+	other, err := FromReaderWithOptions(io.MultiReader(bytes.NewReader([]byte("x"))), -1, opts)
+	if err != nil {
+		t.Fatalf("FromReaderWithOptions failed: %v", err)
+	}
+	if _, err := other.Read(make([]byte, 1)); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	data := make([]byte, blockSize)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	b, err := FromReaderWithOptions(io.MultiReader(bytes.NewReader(data)), int64(len(data)), opts)
+	if err != nil {
+		t.Fatalf("FromReaderWithOptions failed: %v", err)
+	}
+	defer b.Close()
+
+	if _, err := b.Read(make([]byte, 16)); !errors.Is(err, ErrBufferBudgetExceeded) {
+		t.Errorf("Read: expected ErrBufferBudgetExceeded, got %s", formatAny(err))
+	}
+	if expect, actual := int64(len(data)), b.BytesRemaining(); expect != actual {
+		t.Errorf("BytesRemaining: expected %d, got %d", expect, actual)
+	}
+
+	// Once the other Body releases its block, the same Body and its copies
+	// can read everything.
+	_ = other.Close()
+
+	dupe, err := b.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	defer dupe.Close()
+
+	for index, x := range []Body{b, dupe} {
+		raw, err := io.ReadAll(x)
+		if err != nil {
+			t.Errorf("%d: ReadAll failed: %s", index, formatAny(err))
+		}
+		if !bytes.Equal(raw, data) {
+			t.Errorf("%d: ReadAll: expected %d bytes, got %d", index, len(data), len(raw))
+		}
+	}
+}
+
+func TestBudget_SpillWhenExceeded(t *testing.T) {
+	dir := t.TempDir()
+	budget := NewBudget(blockSize)
+
+	data := make([]byte, 4*blockSize)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	b1, err := FromReaderWithOptions(io.MultiReader(bytes.NewReader(data)), -1, &BufferOptions{
+		SpillThreshold: 1 << 30,
+		SpillDir:       dir,
+		Budget:         budget,
+	})
+	if err != nil {
+		t.Fatalf("FromReaderWithOptions failed: %v", err)
+	}
+	defer b1.Close()
+
+	b2, err := b1.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	defer b2.Close()
+
+	raw, err := io.ReadAll(b1)
+	if err != nil {
+		t.Errorf("ReadAll failed: %v", err)
+	}
+	if !bytes.Equal(raw, data) {
+		t.Errorf("ReadAll: output does not match input")
+	}
+	if expect, actual := 1, spillDirEntriesForTest(t, dir); expect != actual {
+		t.Errorf("expected %d spill file, got %d", expect, actual)
+	}
+	if expect, actual := int64(0), budget.Used(); expect != actual {
+		t.Errorf("Used after spill: expected %d, got %d", expect, actual)
+	}
+}
@@ -1,30 +1,281 @@
 package body
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 	"io/fs"
+	"os"
 	"sync"
 
 	"github.com/chronos-tachyon/assert"
+	"github.com/chronos-tachyon/bufferpool"
 )
 
 const blockSize = 65536
 
+// bufferBlock is one fixed-size block of buffered bytes, whose storage is
+// borrowed from bufferpool.
+type bufferBlock struct {
+	buf  *bytes.Buffer
+	data []byte
+}
+
+func newBufferBlock() bufferBlock {
+	buf := bufferpool.Get()
+	buf.Grow(blockSize)
+	return bufferBlock{buf: buf, data: buf.Bytes()[0:blockSize]}
+}
+
+func (block bufferBlock) release() {
+	bufferpool.Put(block.buf)
+}
+
+// BufferOptions controls how a Body buffers a Reader which provides neither
+// io.ReaderAt nor io.Seeker.  Such a Body keeps every byte between the
+// slowest and the fastest of its copies, so that each copy can read them.
+type BufferOptions struct {
+	// SpillThreshold, if positive, is the largest number of buffered bytes
+	// to hold in memory.  Once more bytes than this must be buffered, they
+	// are moved to a temporary file, and all further buffering uses that
+	// file.  The file is removed when the last copy of the Body is closed.
+	SpillThreshold int64
+
+	// SpillDir is the directory in which to create the temporary file.  If
+	// empty, os.TempDir() is used.
+	SpillDir string
+
+	// Retain, if true, keeps every byte read from the Reader until the
+	// last copy of the Body is closed, rather than only the bytes which
+	// some copy has yet to read.  This allows the Body to provide
+	// io.ReaderAt and io.Seeker, reading forward from the Reader as
+	// needed.
+	Retain bool
+
+	// Budget is charged for the memory used to buffer bytes.  If nil,
+	// DefaultBudget is used.
+	//
+	// If the Budget is exhausted, then the buffered bytes are spilled to
+	// disk if SpillThreshold is positive; otherwise, Read fails with a
+	// BufferBudgetExceededError.  Such a failure is not permanent: Read
+	// may be retried once other Bodies have released some of the Budget.
+	//
+	Budget *Budget
+}
+
+// DefaultBufferOptions is used by FromReader and FromReaderAndLength.  By
+// default, all buffering happens in memory.
+var DefaultBufferOptions BufferOptions
+
 type bufferedCommon struct {
-	mu     sync.Mutex
-	r      io.Reader
-	err    error
-	bodies map[*bufferedBody]struct{}
-	bytes  []byte
-	length int64
-	start  int64
-	refcnt int32
+	mu        sync.Mutex
+	r         io.Reader
+	err       error
+	bodies    map[*bufferedBody]struct{}
+	blocks    []bufferBlock
+	blockBase int64
+	memLen    int64
+	length    int64
+	start     int64
+	refcnt    int32
+	opts      BufferOptions
+	budget    *Budget
+	file      *os.File
+	fileBase  int64
+	fileLen   int64
+}
+
+// end returns the offset just past the last buffered byte.
+func (common *bufferedCommon) end() int64 {
+	if common.file != nil {
+		return common.fileBase + common.fileLen
+	}
+	return common.blockBase + common.memLen
+}
+
+// copyOut copies buffered bytes, starting at the given offset, to p.  The
+// caller must ensure that all of them lie within the in-memory buffer.
+func (common *bufferedCommon) copyOut(p []byte, offset int64) {
+	rel := offset - common.blockBase
+	for n := 0; n < len(p); {
+		i := rel / blockSize
+		j := rel % blockSize
+		m := copy(p[n:], common.blocks[i].data[j:])
+		n += m
+		rel += int64(m)
+	}
+}
+
+// releaseBlocksBefore returns to the pool every block whose bytes all lie
+// before the given offset.
+func (common *bufferedCommon) releaseBlocksBefore(offset int64) {
+	if common.opts.Retain {
+		return
+	}
+
+	var count int
+	for count < len(common.blocks) && common.blockBase+blockSize <= offset {
+		common.blocks[count].release()
+		common.blocks[count] = bufferBlock{}
+		common.blockBase += blockSize
+		common.memLen -= blockSize
+		count++
+	}
+	if count > 0 {
+		common.blocks = common.blocks[count:]
+		common.budget.release(int64(count) * blockSize)
+	}
+}
+
+// releaseBlocks returns every block to the pool.
+func (common *bufferedCommon) releaseBlocks() {
+	for _, block := range common.blocks {
+		block.release()
+	}
+	common.dropBlocks()
+}
+
+// dropBlocks forgets every block without returning them to the pool, so that
+// a concurrent WriteTo may safely finish writing from one of them.
+func (common *bufferedCommon) dropBlocks() {
+	common.budget.release(int64(len(common.blocks)) * blockSize)
+	common.blocks = nil
+	common.blockBase += common.memLen
+	common.memLen = 0
+}
+
+// spill moves the buffered bytes from memory to a new temporary file.
+func (common *bufferedCommon) spill() error {
+	f, err := os.CreateTemp(common.opts.SpillDir, "morehttp-body-*")
+	if err != nil {
+		return fmt.Errorf("failed to create spill file: %w", err)
+	}
+
+	end := common.end()
+	for offset := common.blockBase; offset < end; {
+		rel := offset - common.blockBase
+		chunk := common.blocks[rel/blockSize].data[rel%blockSize:]
+		if x := end - offset; int64(len(chunk)) > x {
+			chunk = chunk[0:x]
+		}
+
+		if _, err := f.Write(chunk); err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+			return fmt.Errorf("failed to write spill file: %w", err)
+		}
+		offset += int64(len(chunk))
+	}
+
+	common.file = f
+	common.fileBase = common.blockBase
+	common.fileLen = end - common.blockBase
+	common.dropBlocks()
+	return nil
+}
+
+// removeFile closes and removes the temporary file, if any.
+func (common *bufferedCommon) removeFile() error {
+	f := common.file
+	if f == nil {
+		return nil
+	}
+
+	common.file = nil
+	common.fileBase = 0
+	common.fileLen = 0
+
+	err := f.Close()
+	if err2 := os.Remove(f.Name()); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// fillMemory reads up to numToRead more bytes from the Reader into memory,
+// adding blocks as needed.  Returns a BufferBudgetExceededError, without
+// reading anything, if the Budget cannot cover the additional blocks.
+func (common *bufferedCommon) fillMemory(numToRead int64) error {
+	numBlocks := int((common.memLen + numToRead + blockSize - 1) / blockSize)
+	if extra := numBlocks - len(common.blocks); extra > 0 {
+		if err := common.budget.acquire(int64(extra) * blockSize); err != nil {
+			return err
+		}
+		for i := 0; i < extra; i++ {
+			common.blocks = append(common.blocks, newBufferBlock())
+		}
+	}
+
+	for numToRead > 0 {
+		i := common.memLen / blockSize
+		j := common.memLen % blockSize
+		x := blockSize - j
+		if x > numToRead {
+			x = numToRead
+		}
+
+		n, err := common.r.Read(common.blocks[i].data[j : j+x])
+
+		n64 := int64(n)
+		assert.Assertf(n64 >= 0, "Read must return %d >= 0", n64)
+		assert.Assertf(n64 <= x, "Read must return %d <= %d", n64, x)
+
+		common.memLen += n64
+		numToRead -= n64
+
+		if err != nil {
+			common.length = common.end()
+			common.err = err
+			break
+		}
+	}
+	return nil
+}
+
+// fillFile reads up to numToRead more bytes from the Reader into the
+// temporary file.
+func (common *bufferedCommon) fillFile(numToRead int64) {
+	bufLen := numToRead
+	if bufLen > blockSize {
+		bufLen = blockSize
+	}
+	buf := make([]byte, bufLen)
+
+	for numToRead > 0 {
+		x := numToRead
+		if x > bufLen {
+			x = bufLen
+		}
+
+		n, err := common.r.Read(buf[0:x])
+
+		n64 := int64(n)
+		assert.Assertf(n64 >= 0, "Read must return %d >= 0", n64)
+		assert.Assertf(n64 <= x, "Read must return %d <= %d", n64, x)
+
+		if n64 > 0 {
+			if _, err2 := common.file.WriteAt(buf[0:n], common.fileLen); err2 != nil {
+				common.length = common.end()
+				common.err = fmt.Errorf("failed to write spill file: %w", err2)
+				return
+			}
+			common.fileLen += n64
+			numToRead -= n64
+		}
+
+		if err != nil {
+			common.length = common.end()
+			common.err = err
+			return
+		}
+	}
 }
 
 func (common *bufferedCommon) ref(body *bufferedBody) {
 	common.mu.Lock()
 	defer common.mu.Unlock()
 
+	common.budget.addBody(1)
 	common.bodies[body] = struct{}{}
 	if body.offset == common.start {
 		common.refcnt++
@@ -35,6 +286,7 @@ func (common *bufferedCommon) unref(body *bufferedBody) error {
 	common.mu.Lock()
 	defer common.mu.Unlock()
 
+	common.budget.addBody(-1)
 	delete(common.bodies, body)
 
 	if len(common.bodies) > 0 {
@@ -49,10 +301,13 @@ func (common *bufferedCommon) unref(body *bufferedBody) error {
 
 	r := common.r
 
+	err2 := common.removeFile()
+	common.releaseBlocks()
+
 	common.r = nil
 	common.err = nil
 	common.bodies = nil
-	common.bytes = nil
+	common.blockBase = 0
 	common.length = 0
 	common.start = 0
 
@@ -60,12 +315,15 @@ func (common *bufferedCommon) unref(body *bufferedBody) error {
 	if c, cOK := r.(io.Closer); cOK {
 		err = c.Close()
 	}
+	if err == nil {
+		err = err2
+	}
 	return err
 }
 
 func (common *bufferedCommon) advance(oldOffset, newOffset int64) {
 	beginBuffer := common.start
-	endBuffer := beginBuffer + int64(len(common.bytes))
+	endBuffer := common.end()
 
 	assert.Assertf(oldOffset >= beginBuffer, "%d >= %d", oldOffset, beginBuffer)
 	assert.Assertf(newOffset >= oldOffset, "%d >= %d", newOffset, oldOffset)
@@ -96,9 +354,8 @@ func (common *bufferedCommon) updateStart() {
 	assert.Assertf(beginBufferNew >= beginBufferOld, "new start %d >= old start %d", beginBufferNew, beginBufferOld)
 	assert.Assertf(refcnt >= 1, "new refcnt %d >= %d", refcnt, 1)
 
-	i := beginBufferNew - beginBufferOld
-	common.bytes = common.bytes[i:]
 	common.start = beginBufferNew
+	common.releaseBlocksBefore(beginBufferNew)
 	common.refcnt = refcnt
 }
 
@@ -138,6 +395,85 @@ func (body *bufferedBody) BytesRemaining() int64 {
 	return (endOffset - beginOffset)
 }
 
+// fill reads more bytes from the Reader, if needed and if no error has yet
+// occurred, so that the buffer extends to at least endOffset.  Returns the new
+// end of the buffer.
+//
+// If the Budget cannot cover the bytes and they cannot be spilled to disk,
+// then fill also returns a BufferBudgetExceededError.  Unlike other errors,
+// it is not saved in common.err, as a later call may succeed once some other
+// Body has released its share of the Budget.
+//
+func (common *bufferedCommon) fill(endOffset int64) (int64, error) {
+	endBuffer := common.end()
+	if common.err != nil || endOffset <= endBuffer {
+		return endBuffer, nil
+	}
+
+	numToRead := (endOffset - endBuffer)
+	numToRead = int64(uint64(numToRead+blockSize-1) &^ uint64(blockSize-1))
+
+	eof := false
+	if common.length >= 0 {
+		assert.Assertf(endBuffer <= common.length, "%d <= %d", endBuffer, common.length)
+		remain := (common.length - endBuffer)
+		if numToRead >= remain {
+			numToRead = remain
+			eof = true
+		}
+	}
+
+	threshold := common.opts.SpillThreshold
+	if common.file == nil && threshold > 0 && common.memLen+numToRead > threshold {
+		if err := common.spill(); err != nil {
+			common.length = endBuffer
+			common.err = err
+		}
+	}
+
+	if common.err == nil && common.file == nil {
+		err := common.fillMemory(numToRead)
+		if err != nil && threshold <= 0 {
+			return endBuffer, err
+		}
+		if err != nil {
+			err = common.spill()
+		}
+		if err != nil {
+			common.length = endBuffer
+			common.err = err
+		}
+	}
+	if common.err == nil && common.file != nil {
+		common.fillFile(numToRead)
+	}
+	endBuffer = common.end()
+
+	if eof && common.err == nil {
+		common.length = endBuffer
+		common.err = io.EOF
+	}
+	return endBuffer, nil
+}
+
+// readBuffered copies buffered bytes, starting at the given offset, to p.
+// The caller must ensure that all of them have been buffered.
+func (common *bufferedCommon) readBuffered(p []byte, offset int64) (int, error) {
+	if common.file == nil {
+		common.copyOut(p, offset)
+		return len(p), nil
+	}
+
+	n, err := common.file.ReadAt(p, offset-common.fileBase)
+	if n < len(p) {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return n, fmt.Errorf("failed to read spill file: %w", err)
+	}
+	return n, nil
+}
+
 func (body *bufferedBody) Read(p []byte) (int, error) {
 	body.mu.Lock()
 	defer body.mu.Unlock()
@@ -154,78 +490,117 @@ func (body *bufferedBody) Read(p []byte) (int, error) {
 		return 0, common.err
 	}
 
-	beginBuffer := common.start
-	endBuffer := beginBuffer + int64(len(common.bytes))
 	beginOffset := body.offset
 	endOffset := beginOffset + int64(len(p))
 
-	assert.Assertf(beginOffset >= beginBuffer, "%d >= %d", beginOffset, beginBuffer)
+	assert.Assertf(beginOffset >= common.start, "%d >= %d", beginOffset, common.start)
 
-	if common.err == nil && endOffset > endBuffer {
-		numToRead := (endOffset - endBuffer)
-		numToRead = int64(uint64(numToRead+blockSize-1) &^ uint64(blockSize-1))
+	endBuffer, budgetErr := common.fill(endOffset)
+	if budgetErr != nil && beginOffset >= endBuffer {
+		return 0, budgetErr
+	}
 
-		eof := false
-		if common.length >= 0 {
-			assert.Assertf(endBuffer <= common.length, "%d <= %d", endBuffer, common.length)
-			remain := (common.length - endBuffer)
-			if numToRead >= remain {
-				numToRead = remain
-				eof = true
-			}
+	var err error
+	if endOffset > endBuffer {
+		endOffset = endBuffer
+		if budgetErr == nil {
+			err = common.err
+			body.eof = true
 		}
+	}
 
-		bufLen := (endBuffer - beginBuffer)
-		bufCap := bufLen
-		bufCap += numToRead
-		bufNew := make([]byte, bufCap)
-		copy(bufNew[0:bufLen], common.bytes[0:bufLen])
-		common.bytes = bufNew[0:bufLen]
+	n := int(endOffset - beginOffset)
+	n, err2 := common.readBuffered(p[0:n], beginOffset)
+	if err2 != nil {
+		endOffset = beginOffset + int64(n)
+		err = err2
+		body.eof = false
+	}
+	body.offset = endOffset
+	common.advance(beginOffset, endOffset)
+	return n, err
+}
 
-		for numToRead > 0 {
-			i := bufLen
-			x := numToRead
-			j := i + x
+// WriteTo writes the remaining bytes to w, straight from the shared buffer,
+// reading more from the Reader one block at a time.
+func (body *bufferedBody) WriteTo(w io.Writer) (int64, error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
 
-			n, err := common.r.Read(bufNew[i:j])
+	if body.closed {
+		return 0, fs.ErrClosed
+	}
 
-			n64 := int64(n)
-			assert.Assertf(n64 >= 0, "Read must return %d >= 0", n64)
-			assert.Assertf(n64 <= x, "Read must return %d <= %d", n64, x)
+	common := body.common
+	common.mu.Lock()
+	defer common.mu.Unlock()
 
-			bufLen += n64
-			numToRead -= n64
+	var scratch []byte
+	var total int64
+	for {
+		if body.eof {
+			break
+		}
 
-			common.bytes = bufNew[0:bufLen]
-			endBuffer = beginBuffer + bufLen
+		beginOffset := body.offset
+		endBuffer, err := common.fill(beginOffset + 1)
+		if err != nil {
+			return total, err
+		}
+		if beginOffset >= endBuffer {
+			body.eof = true
+			break
+		}
 
+		// Blocks at or after our offset are never returned to the pool
+		// while we remain open, so the chunk stays valid while the lock
+		// is released.
+		var chunk []byte
+		if common.file == nil {
+			rel := beginOffset - common.blockBase
+			chunk = common.blocks[rel/blockSize].data[rel%blockSize:]
+			if x := endBuffer - beginOffset; int64(len(chunk)) > x {
+				chunk = chunk[0:x]
+			}
+		} else {
+			x := endBuffer - beginOffset
+			if x > blockSize {
+				x = blockSize
+			}
+			if scratch == nil {
+				scratch = make([]byte, blockSize)
+			}
+			n, err := common.readBuffered(scratch[0:x], beginOffset)
 			if err != nil {
-				common.length = endBuffer
-				common.err = err
-				break
+				return total, err
 			}
+			chunk = scratch[0:n]
 		}
 
-		if eof && common.err == nil {
-			common.length = endBuffer
-			common.err = io.EOF
+		common.mu.Unlock()
+		n, err := w.Write(chunk)
+		common.mu.Lock()
+
+		assert.Assertf(n >= 0, "Write must return %d >= 0", n)
+		assert.Assertf(n <= len(chunk), "Write must return %d <= %d", n, len(chunk))
+
+		if n > 0 {
+			total += int64(n)
+			body.offset = beginOffset + int64(n)
+			common.advance(beginOffset, body.offset)
+		}
+		if err == nil && n < len(chunk) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			return total, err
 		}
 	}
 
-	var err error
-	if endOffset > endBuffer {
-		endOffset = endBuffer
-		err = common.err
-		body.eof = true
+	if common.err == io.EOF {
+		return total, nil
 	}
-
-	i := int(beginOffset - beginBuffer)
-	j := int(endOffset - beginBuffer)
-	n := (j - i)
-	copy(p[0:n], common.bytes[i:j])
-	body.offset = endOffset
-	common.advance(beginOffset, endOffset)
-	return n, err
+	return total, common.err
 }
 
 func (body *bufferedBody) Close() error {
@@ -279,5 +654,124 @@ func (body *bufferedBody) Unwrap() io.Reader {
 }
 
 var (
-	_ Body = (*bufferedBody)(nil)
+	_ Body        = (*bufferedBody)(nil)
+	_ io.WriterTo = (*bufferedBody)(nil)
+)
+
+// bufferedAtBody is a bufferedBody whose bytes are all retained, which allows
+// it to provide io.ReaderAt and io.Seeker.  As with the other seekable Bodies,
+// ReadAt offsets are relative to the start of the Body, not to the cursor.
+type bufferedAtBody struct {
+	*bufferedBody
+}
+
+func (body *bufferedAtBody) Seek(offset int64, whence int) (int64, error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return -1, fs.ErrClosed
+	}
+
+	common := body.common
+	common.mu.Lock()
+	defer common.mu.Unlock()
+
+	switch whence {
+	case io.SeekStart:
+		if offset < 0 {
+			return -1, NegativeStartOffsetSeekError{offset}
+		}
+	case io.SeekCurrent:
+		offset += body.offset
+	case io.SeekEnd:
+		// The length is only known once the Reader has been consumed.
+		for common.err == nil {
+			if _, err := common.fill(common.end() + blockSize); err != nil {
+				return -1, err
+			}
+		}
+		if common.err != io.EOF {
+			return -1, common.err
+		}
+		offset += common.length
+	default:
+		return -1, UnknownWhenceSeekError{whence}
+	}
+
+	if offset < 0 {
+		return -1, NegativeComputedOffsetSeekError{offset}
+	}
+
+	endBuffer, err := common.fill(offset)
+	if err != nil {
+		return -1, err
+	}
+	if offset > endBuffer {
+		offset = endBuffer
+	}
+
+	// No bytes are ever released while Retain is set, so the oldest
+	// cursor may safely move backward.
+	body.offset = offset
+	body.eof = false
+	common.start = 0
+	common.updateStart()
+	return offset, nil
+}
+
+func (body *bufferedAtBody) ReadAt(p []byte, offset int64) (int, error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return 0, fs.ErrClosed
+	}
+
+	if offset < 0 {
+		return 0, fmt.Errorf("ReadAt error: offset %d is negative", offset)
+	}
+
+	common := body.common
+	common.mu.Lock()
+	defer common.mu.Unlock()
+
+	endOffset := offset + int64(len(p))
+	endBuffer, budgetErr := common.fill(endOffset)
+
+	var err error
+	if endOffset > endBuffer {
+		if offset > endBuffer {
+			offset = endBuffer
+		}
+		endOffset = endBuffer
+		err = common.err
+		if budgetErr != nil {
+			err = budgetErr
+		}
+	}
+
+	n, err2 := common.readBuffered(p[0:endOffset-offset], offset)
+	if err2 != nil {
+		err = err2
+	}
+	return n, err
+}
+
+func (body *bufferedAtBody) Copy() (Body, error) {
+	dupe, err := body.bufferedBody.Copy()
+	if err != nil {
+		return nil, err
+	}
+	if x, ok := dupe.(*bufferedBody); ok {
+		return &bufferedAtBody{x}, nil
+	}
+	return dupe, nil
+}
+
+var (
+	_ Body        = (*bufferedAtBody)(nil)
+	_ io.Seeker   = (*bufferedAtBody)(nil)
+	_ io.ReaderAt = (*bufferedAtBody)(nil)
+	_ io.WriterTo = (*bufferedAtBody)(nil)
 )
@@ -1,7 +1,14 @@
 package body
 
 import (
+	"bytes"
+	"errors"
 	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/chronos-tachyon/morehttp/internal/mockreader"
@@ -103,3 +110,367 @@ func TestBufferedBody_NoReadAtNoSeekNoStat(t *testing.T) {
 		ShortBodyUnknownLength: true,
 	})
 }
+
+func spillDirEntriesForTest(t *testing.T, dir string) int {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	return len(entries)
+}
+
+func TestBufferedBody_Spill(t *testing.T) {
+	dir := t.TempDir()
+
+	data := make([]byte, 5*blockSize+123)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	b1, err := FromReaderWithOptions(io.MultiReader(bytes.NewReader(data)), -1, &BufferOptions{
+		SpillThreshold: 2 * blockSize,
+		SpillDir:       dir,
+	})
+	if err != nil {
+		t.Fatalf("FromReaderWithOptions failed: %v", err)
+	}
+
+	b2, err := b1.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	raw1, err := io.ReadAll(b1)
+	if err != nil {
+		t.Errorf("ReadAll failed: %v", err)
+	}
+	if !bytes.Equal(raw1, data) {
+		t.Errorf("ReadAll: first copy does not match input")
+	}
+	if expect, actual := 1, spillDirEntriesForTest(t, dir); expect != actual {
+		t.Errorf("expected %d spill file, got %d", expect, actual)
+	}
+
+	if err := b1.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+
+	raw2, err := io.ReadAll(b2)
+	if err != nil {
+		t.Errorf("ReadAll failed: %v", err)
+	}
+	if !bytes.Equal(raw2, data) {
+		t.Errorf("ReadAll: second copy does not match input")
+	}
+
+	if err := b2.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if expect, actual := 0, spillDirEntriesForTest(t, dir); expect != actual {
+		t.Errorf("expected %d spill files after final Close, got %d", expect, actual)
+	}
+}
+
+func TestBufferedBody_SpillBelowThreshold(t *testing.T) {
+	dir := t.TempDir()
+
+	b, err := FromReaderWithOptions(io.MultiReader(strings.NewReader("abcd")), -1, &BufferOptions{
+		SpillThreshold: blockSize,
+		SpillDir:       dir,
+	})
+	if err != nil {
+		t.Fatalf("FromReaderWithOptions failed: %v", err)
+	}
+	defer b.Close()
+
+	if _, err := io.ReadAll(b); err != nil {
+		t.Errorf("ReadAll failed: %v", err)
+	}
+	if expect, actual := 0, spillDirEntriesForTest(t, dir); expect != actual {
+		t.Errorf("expected %d spill files, got %d", expect, actual)
+	}
+}
+
+func TestBufferedBody_SpillError(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+
+	data := make([]byte, 3*blockSize)
+	b1, err := FromReaderWithOptions(io.MultiReader(bytes.NewReader(data)), -1, &BufferOptions{
+		SpillThreshold: blockSize,
+		SpillDir:       dir,
+	})
+	if err != nil {
+		t.Fatalf("FromReaderWithOptions failed: %v", err)
+	}
+	defer b1.Close()
+
+	b2, err := b1.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	defer b2.Close()
+
+	_, err = io.ReadAll(b1)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadAll: expected fs.ErrNotExist, got %s", formatAny(err))
+	}
+}
+
+func TestBufferedBody_WriteTo(t *testing.T) {
+	dir := t.TempDir()
+
+	data := make([]byte, 5*blockSize+123)
+	for i := range data {
+		data[i] = byte(i * 13)
+	}
+
+	for _, threshold := range []int64{0, 2 * blockSize} {
+		b1, err := FromReaderWithOptions(io.MultiReader(bytes.NewReader(data)), -1, &BufferOptions{
+			SpillThreshold: threshold,
+			SpillDir:       dir,
+		})
+		if err != nil {
+			t.Fatalf("FromReaderWithOptions failed: %v", err)
+		}
+
+		b2, err := b1.Copy()
+		if err != nil {
+			t.Fatalf("Copy failed: %v", err)
+		}
+
+		// Consume part of the first copy via Read, so that WriteTo
+		// starts from the middle of a block.
+		head := make([]byte, 100)
+		if _, err := io.ReadFull(b1, head); err != nil {
+			t.Errorf("ReadFull failed: %v", err)
+		}
+
+		var buf1 bytes.Buffer
+		n, err := b1.(io.WriterTo).WriteTo(&buf1)
+		if err != nil {
+			t.Errorf("WriteTo failed: %v", err)
+		}
+		if expect := int64(len(data) - len(head)); n != expect {
+			t.Errorf("WriteTo: expected %d bytes, got %d", expect, n)
+		}
+		if !bytes.Equal(append(head, buf1.Bytes()...), data) {
+			t.Errorf("WriteTo: first copy does not match input")
+		}
+		if expect, actual := int64(0), b1.BytesRemaining(); expect != actual {
+			t.Errorf("BytesRemaining: expected %d, got %d", expect, actual)
+		}
+
+		var buf2 bytes.Buffer
+		if _, err := b2.(io.WriterTo).WriteTo(&buf2); err != nil {
+			t.Errorf("WriteTo failed: %v", err)
+		}
+		if !bytes.Equal(buf2.Bytes(), data) {
+			t.Errorf("WriteTo: second copy does not match input")
+		}
+
+		_ = b1.Close()
+		_ = b2.Close()
+		if expect, actual := 0, spillDirEntriesForTest(t, dir); expect != actual {
+			t.Errorf("expected %d spill files after final Close, got %d", expect, actual)
+		}
+	}
+}
+
+func TestBufferedBody_Retain(t *testing.T) {
+	short, err := FromReaderWithOptions(io.MultiReader(strings.NewReader("abcd")), -1, &BufferOptions{Retain: true})
+	if err != nil {
+		t.Fatalf("FromReaderWithOptions failed: %v", err)
+	}
+
+	RunBodyTests(t, &TestOptions{
+		ShortBody:              short,
+		ShortBodyUnknownLength: true,
+	})
+}
+
+func TestBufferedBody_RetainReadAt(t *testing.T) {
+	data := make([]byte, 3*blockSize+5)
+	for i := range data {
+		data[i] = byte(i * 3)
+	}
+
+	b1, err := FromReaderWithOptions(io.MultiReader(bytes.NewReader(data)), -1, &BufferOptions{Retain: true})
+	if err != nil {
+		t.Fatalf("FromReaderWithOptions failed: %v", err)
+	}
+	defer b1.Close()
+
+	x, ok := b1.(io.ReaderAt)
+	if !ok {
+		t.Fatalf("expected %T to implement io.ReaderAt", b1)
+	}
+
+	// ReadAt reads forward from the Reader without moving the read offset.
+	p := make([]byte, 10)
+	n, err := x.ReadAt(p, 2*blockSize)
+	if err != nil || n != len(p) {
+		t.Errorf("ReadAt: expected (%d, <nil>), got (%d, %s)", len(p), n, formatAny(err))
+	}
+	if !bytes.Equal(p, data[2*blockSize:2*blockSize+10]) {
+		t.Errorf("ReadAt: data mismatch")
+	}
+	if expect, actual := int64(len(data)), b1.BytesRemaining(); actual != -1 && actual != expect {
+		t.Errorf("BytesRemaining: expected %d or -1, got %d", expect, actual)
+	}
+
+	// Bytes already consumed by Read remain available to ReadAt.
+	raw, err := io.ReadAll(b1)
+	if err != nil {
+		t.Errorf("ReadAll failed: %v", err)
+	}
+	if !bytes.Equal(raw, data) {
+		t.Errorf("ReadAll: data mismatch")
+	}
+	n, err = x.ReadAt(p, 0)
+	if err != nil || n != len(p) || !bytes.Equal(p, data[:10]) {
+		t.Errorf("ReadAt after ReadAll: expected (%d, <nil>), got (%d, %s)", len(p), n, formatAny(err))
+	}
+
+	// A ReadAt which runs past the end returns io.EOF.
+	n, err = x.ReadAt(p, int64(len(data)-4))
+	if err != io.EOF || n != 4 {
+		t.Errorf("ReadAt at end: expected (4, io.EOF), got (%d, %s)", n, formatAny(err))
+	}
+
+	// Copies provide io.ReaderAt too.
+	b2, err := b1.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if _, ok := b2.(io.ReaderAt); !ok {
+		t.Errorf("expected Copy result %T to implement io.ReaderAt", b2)
+	}
+	_ = b2.Close()
+}
+
+func benchmarkBufferedBodyCopies(b *testing.B, numCopies int) {
+	data := make([]byte, 4<<20)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data) * numCopies))
+	b.ResetTimer()
+
+	for iter := 0; iter < b.N; iter++ {
+		first, err := FromReader(io.MultiReader(bytes.NewReader(data)))
+		if err != nil {
+			b.Fatalf("FromReader failed: %v", err)
+		}
+
+		bodies := make([]Body, numCopies)
+		bodies[0] = first
+		for index := 1; index < numCopies; index++ {
+			bodies[index], err = first.Copy()
+			if err != nil {
+				b.Fatalf("Copy failed: %v", err)
+			}
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(numCopies)
+		for _, body := range bodies {
+			go func(body Body) {
+				defer wg.Done()
+				buf := make([]byte, 32<<10)
+				_, _ = io.CopyBuffer(io.Discard, struct{ io.Reader }{body}, buf)
+				_ = body.Close()
+			}(body)
+		}
+		wg.Wait()
+	}
+}
+
+func BenchmarkBufferedBody_Copies1(b *testing.B) {
+	benchmarkBufferedBodyCopies(b, 1)
+}
+
+func BenchmarkBufferedBody_Copies2(b *testing.B) {
+	benchmarkBufferedBodyCopies(b, 2)
+}
+
+func BenchmarkBufferedBody_Copies8(b *testing.B) {
+	benchmarkBufferedBodyCopies(b, 8)
+}
+
+func TestBufferedBody_RetainSliceAfterRead(t *testing.T) {
+	const content = "0123456789abcdef"
+
+	b, err := FromReaderWithOptions(io.MultiReader(strings.NewReader(content)), int64(len(content)), &BufferOptions{Retain: true})
+	if err != nil {
+		t.Fatalf("FromReaderWithOptions failed: %v", err)
+	}
+	defer b.Close()
+
+	p := make([]byte, 4)
+	if _, err := io.ReadFull(b, p); err != nil {
+		t.Fatalf("ReadFull failed: %v", err)
+	}
+
+	// The cursor is recoverable via Seek, so Slice works in terms of the
+	// whole Body, just as for any other seekable Body.
+	if expect, actual := int64(len(content)-4), b.BytesRemaining(); expect != actual {
+		t.Errorf("BytesRemaining: expected %d, got %d", expect, actual)
+	}
+	if cursor, err := b.(io.Seeker).Seek(0, io.SeekCurrent); err != nil || cursor != 4 {
+		t.Errorf("Seek: expected (4, <nil>), got (%d, %s)", cursor, formatAny(err))
+	}
+
+	sliced, err := Slice(b, 10, 6)
+	if err != nil {
+		t.Fatalf("Slice failed: %v", err)
+	}
+	defer sliced.Close()
+
+	raw, err := io.ReadAll(sliced)
+	if err != nil {
+		t.Errorf("ReadAll failed: %v", err)
+	}
+	if expect, actual := "abcdef", string(raw); expect != actual {
+		t.Errorf("Slice: expected %q, got %q", expect, actual)
+	}
+
+	// Seeking backward makes consumed bytes readable again.
+	if n, err := b.(io.Seeker).Seek(-2, io.SeekEnd); err != nil || n != int64(len(content)-2) {
+		t.Errorf("Seek: expected (%d, <nil>), got (%d, %s)", len(content)-2, n, formatAny(err))
+	}
+	if n, err := b.(io.Seeker).Seek(1, io.SeekStart); err != nil || n != 1 {
+		t.Errorf("Seek: expected (1, <nil>), got (%d, %s)", n, formatAny(err))
+	}
+	raw, err = io.ReadAll(b)
+	if err != nil {
+		t.Errorf("ReadAll failed: %v", err)
+	}
+	if expect, actual := content[1:], string(raw); expect != actual {
+		t.Errorf("ReadAll after Seek: expected %q, got %q", expect, actual)
+	}
+}
+
+func TestBufferedBody_RetainSeekEndUnknownLength(t *testing.T) {
+	data := make([]byte, 2*blockSize+7)
+	b, err := FromReaderWithOptions(io.MultiReader(bytes.NewReader(data)), -1, &BufferOptions{Retain: true})
+	if err != nil {
+		t.Fatalf("FromReaderWithOptions failed: %v", err)
+	}
+	defer b.Close()
+
+	if n, err := b.(io.Seeker).Seek(0, io.SeekEnd); err != nil || n != int64(len(data)) {
+		t.Errorf("Seek: expected (%d, <nil>), got (%d, %s)", len(data), n, formatAny(err))
+	}
+	if expect, actual := int64(0), b.BytesRemaining(); expect != actual {
+		t.Errorf("BytesRemaining: expected %d, got %d", expect, actual)
+	}
+	if n, err := b.(io.Seeker).Seek(-7, io.SeekCurrent); err != nil || n != int64(2*blockSize) {
+		t.Errorf("Seek: expected (%d, <nil>), got (%d, %s)", 2*blockSize, n, formatAny(err))
+	}
+	if expect, actual := int64(7), b.BytesRemaining(); expect != actual {
+		t.Errorf("BytesRemaining: expected %d, got %d", expect, actual)
+	}
+}
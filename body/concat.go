@@ -0,0 +1,358 @@
+package body
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"sync"
+
+	"github.com/chronos-tachyon/assert"
+)
+
+type concatBody struct {
+	mu        sync.Mutex
+	parts     []Body
+	bases     []int64
+	starts    []int64
+	errs      []error
+	index     int
+	offset    int64
+	canSeek   bool
+	canReadAt bool
+	eof       bool
+	closed    bool
+}
+
+func (body *concatBody) wrap() Body {
+	switch {
+	case body.canSeek && body.canReadAt:
+		return concatSeekReaderAtBody{body}
+	case body.canSeek:
+		return concatSeekerBody{body}
+	case body.canReadAt:
+		return concatReaderAtBody{body}
+	default:
+		return body
+	}
+}
+
+func (body *concatBody) isRandomAccess() bool {
+	return body.canSeek || body.canReadAt
+}
+
+func (body *concatBody) length() int64 {
+	return body.starts[len(body.parts)]
+}
+
+func (body *concatBody) find(offset int64) int {
+	return sort.Search(len(body.parts), func(i int) bool {
+		return body.starts[i+1] > offset
+	})
+}
+
+func (body *concatBody) nextPart() {
+	if !body.isRandomAccess() {
+		part := body.parts[body.index]
+		body.parts[body.index] = nil
+		if err := part.Close(); err != nil {
+			body.errs = append(body.errs, err)
+		}
+	}
+	body.index++
+}
+
+func (body *concatBody) BytesRemaining() int64 {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed || body.eof {
+		return 0
+	}
+
+	var sum int64
+	for _, part := range body.parts[body.index:] {
+		length := part.BytesRemaining()
+		if length < 0 {
+			return -1
+		}
+		sum += length
+	}
+	return sum
+}
+
+func (body *concatBody) Read(p []byte) (int, error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return 0, fs.ErrClosed
+	}
+
+	if body.eof {
+		return 0, io.EOF
+	}
+
+	var total int
+	for body.index < len(body.parts) {
+		n, err := body.parts[body.index].Read(p[total:])
+		total += n
+		body.offset += int64(n)
+
+		if err == io.EOF {
+			body.nextPart()
+			if len(p) > 0 && total == len(p) {
+				return total, nil
+			}
+			continue
+		}
+
+		return total, err
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	body.eof = true
+	return total, io.EOF
+}
+
+func (body *concatBody) Close() error {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return fs.ErrClosed
+	}
+
+	errs := body.errs
+	for _, part := range body.parts {
+		if part == nil {
+			continue
+		}
+		if err := part.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	body.parts = nil
+	body.bases = nil
+	body.starts = nil
+	body.errs = nil
+	body.index = 0
+	body.offset = 0
+	body.eof = true
+	body.closed = true
+	return joinErrors(errs)
+}
+
+func (body *concatBody) seek(offset int64, whence int) (int64, error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return -1, fs.ErrClosed
+	}
+
+	length := body.length()
+
+	switch whence {
+	case io.SeekStart:
+		if offset < 0 {
+			return -1, NegativeStartOffsetSeekError{offset}
+		}
+	case io.SeekCurrent:
+		offset += body.offset
+	case io.SeekEnd:
+		offset += length
+	default:
+		return -1, UnknownWhenceSeekError{whence}
+	}
+
+	if offset < 0 {
+		return -1, NegativeComputedOffsetSeekError{offset}
+	}
+
+	if offset > length {
+		offset = length
+	}
+
+	index := body.find(offset)
+	for i := index; i < len(body.parts); i++ {
+		var rel int64
+		if i == index {
+			rel = offset - body.starts[i]
+		}
+		_, err := body.parts[i].(io.Seeker).Seek(body.bases[i]+rel, io.SeekStart)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	body.index = index
+	body.offset = offset
+	body.eof = false
+	return offset, nil
+}
+
+func (body *concatBody) readAt(p []byte, offset int64) (int, error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return 0, fs.ErrClosed
+	}
+
+	if offset < 0 {
+		return 0, fmt.Errorf("ReadAt error: offset %d is negative", offset)
+	}
+
+	length := body.length()
+	if offset > length {
+		offset = length
+	}
+
+	var total int
+	for i := body.find(offset); i < len(body.parts) && total < len(p); i++ {
+		q := p[total:]
+		avail := body.starts[i+1] - offset
+		if int64(len(q)) > avail {
+			q = q[:avail]
+		}
+
+		x := len(q)
+		n, err := body.parts[i].(io.ReaderAt).ReadAt(q, body.bases[i]+offset-body.starts[i])
+		assert.Assertf(n >= 0, "ReadAt must return %d >= 0", n)
+		assert.Assertf(n <= x, "ReadAt must return %d <= %d", n, x)
+
+		total += n
+		offset += int64(n)
+
+		if err == io.EOF && n == x {
+			err = nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+
+	if total < len(p) {
+		return total, io.EOF
+	}
+	return total, nil
+}
+
+func (body *concatBody) WriteTo(w io.Writer) (int64, error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return 0, fs.ErrClosed
+	}
+
+	if body.eof {
+		return 0, nil
+	}
+
+	var total int64
+	for body.index < len(body.parts) {
+		n, err := io.Copy(w, body.parts[body.index])
+		total += n
+		body.offset += n
+		if err != nil {
+			return total, err
+		}
+		body.nextPart()
+	}
+
+	body.eof = true
+	return total, nil
+}
+
+func (body *concatBody) Copy() (Body, error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return closedSingleton, nil
+	}
+
+	parts := make([]Body, len(body.parts))
+	for index, part := range body.parts {
+		if part == nil {
+			continue
+		}
+
+		dupe, err := part.Copy()
+		if err != nil {
+			for _, other := range parts[:index] {
+				if other != nil {
+					_ = other.Close()
+				}
+			}
+			return nil, err
+		}
+		parts[index] = dupe
+	}
+
+	dupe := &concatBody{
+		parts:     parts,
+		bases:     body.bases,
+		starts:    body.starts,
+		index:     body.index,
+		offset:    body.offset,
+		canSeek:   body.canSeek,
+		canReadAt: body.canReadAt,
+		eof:       body.eof,
+		closed:    body.closed,
+	}
+	return dupe.wrap(), nil
+}
+
+func (body *concatBody) Unwrap() io.Reader {
+	return nil
+}
+
+type concatSeekerBody struct {
+	*concatBody
+}
+
+func (body concatSeekerBody) Seek(offset int64, whence int) (int64, error) {
+	return body.seek(offset, whence)
+}
+
+type concatReaderAtBody struct {
+	*concatBody
+}
+
+func (body concatReaderAtBody) ReadAt(p []byte, offset int64) (int, error) {
+	return body.readAt(p, offset)
+}
+
+type concatSeekReaderAtBody struct {
+	*concatBody
+}
+
+func (body concatSeekReaderAtBody) Seek(offset int64, whence int) (int64, error) {
+	return body.seek(offset, whence)
+}
+
+func (body concatSeekReaderAtBody) ReadAt(p []byte, offset int64) (int, error) {
+	return body.readAt(p, offset)
+}
+
+var (
+	_ Body        = (*concatBody)(nil)
+	_ io.WriterTo = (*concatBody)(nil)
+	_ Body        = concatSeekerBody{}
+	_ io.Seeker   = concatSeekerBody{}
+	_ io.WriterTo = concatSeekerBody{}
+	_ Body        = concatReaderAtBody{}
+	_ io.ReaderAt = concatReaderAtBody{}
+	_ io.WriterTo = concatReaderAtBody{}
+	_ Body        = concatSeekReaderAtBody{}
+	_ io.Seeker   = concatSeekReaderAtBody{}
+	_ io.ReaderAt = concatSeekReaderAtBody{}
+	_ io.WriterTo = concatSeekReaderAtBody{}
+)
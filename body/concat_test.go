@@ -0,0 +1,142 @@
+package body
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/chronos-tachyon/morehttp/internal/mockreader"
+)
+
+func TestConcatBody(t *testing.T) {
+	b0 := Concat(Empty(), Empty())
+	b1 := Concat(FromString("ab"), Empty(), FromString("cd"))
+
+	if _, ok := b1.(io.Seeker); !ok {
+		t.Errorf("expected %T to implement io.Seeker", b1)
+	}
+	if _, ok := b1.(io.ReaderAt); !ok {
+		t.Errorf("expected %T to implement io.ReaderAt", b1)
+	}
+
+	RunBodyTests(t, &TestOptions{
+		EmptyBody: b0,
+		ShortBody: b1,
+	})
+}
+
+func TestConcatBody_UnknownLength(t *testing.T) {
+	p := make([]byte, 65536)
+	p[0] = 'c'
+	p[1] = 'd'
+	r := mockreader.New(
+		mockreader.ExpectMark("ShortBody-Begin"),
+		mockreader.ExpectMark("Read-Begin"),
+		mockreader.ExpectRead(p, 2, io.EOF),
+		mockreader.ExpectClose(nil),
+		mockreader.ExpectMark("Read-End"),
+		mockreader.ExpectMark("Close-Begin"),
+		mockreader.ExpectMark("Close-End"),
+		mockreader.ExpectMark("AfterClose-Begin"),
+		mockreader.ExpectMark("AfterClose-End"),
+		mockreader.ExpectMark("ShortBody-End"),
+	)
+
+	tail, err := FromReader(mockreader.Wrapper000{Inner: r})
+	if err != nil {
+		t.Errorf("FromReader failed: %v", err)
+		return
+	}
+
+	b := Concat(FromString("ab"), tail)
+
+	if _, ok := b.(io.Seeker); ok {
+		t.Errorf("expected %T not to implement io.Seeker", b)
+	}
+	if _, ok := b.(io.ReaderAt); ok {
+		t.Errorf("expected %T not to implement io.ReaderAt", b)
+	}
+
+	RunBodyTests(t, &TestOptions{
+		ShortMock:              r,
+		ShortBody:              b,
+		ShortBodyUnknownLength: true,
+	})
+}
+
+func TestConcatBody_WriteToAndCopy(t *testing.T) {
+	b := Concat(FromString("abc"), FromString(""), FromString("def"), FromString("ghi"))
+
+	p := make([]byte, 2)
+	if _, err := io.ReadFull(b, p); err != nil {
+		t.Fatalf("ReadFull failed: %v", err)
+	}
+
+	dupe, err := b.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if expect, actual := int64(7), dupe.BytesRemaining(); expect != actual {
+		t.Errorf("BytesRemaining: expected %d, got %d", expect, actual)
+	}
+
+	var buf bytes.Buffer
+	n, err := dupe.(io.WriterTo).WriteTo(&buf)
+	if err != nil {
+		t.Errorf("WriteTo failed: %v", err)
+	}
+	if expect := int64(7); n != expect {
+		t.Errorf("WriteTo: expected %d, got %d", expect, n)
+	}
+	if expect, actual := "cdefghi", buf.String(); expect != actual {
+		t.Errorf("WriteTo: expected %q, got %q", expect, actual)
+	}
+
+	buf.Reset()
+	if _, err := io.Copy(&buf, b); err != nil {
+		t.Errorf("io.Copy failed: %v", err)
+	}
+	if expect, actual := "cdefghi", buf.String(); expect != actual {
+		t.Errorf("io.Copy: expected %q, got %q", expect, actual)
+	}
+
+	if err := dupe.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
+
+func TestConcatBody_CloseErrors(t *testing.T) {
+	errA := errors.New("A")
+	errB := errors.New("B")
+	ra := mockreader.New(mockreader.ExpectClose(errA))
+	rb := mockreader.New(mockreader.ExpectClose(errB))
+
+	a, err := FromReader(mockreader.Wrapper000{Inner: ra})
+	if err != nil {
+		t.Fatalf("FromReader failed: %v", err)
+	}
+	b, err := FromReader(mockreader.Wrapper000{Inner: rb})
+	if err != nil {
+		t.Fatalf("FromReader failed: %v", err)
+	}
+
+	err = Concat(a, FromString("x"), b).Close()
+
+	var multi MultiError
+	if !errors.As(err, &multi) {
+		t.Fatalf("Close: expected MultiError, got %s", formatAny(err))
+	}
+	if expect, actual := 2, len(multi.Errors); expect != actual {
+		t.Errorf("Close: expected %d errors, got %d", expect, actual)
+	}
+	if !errors.Is(err, errA) {
+		t.Errorf("Close: expected errors.Is(err, errA)")
+	}
+	if !errors.Is(err, errB) {
+		t.Errorf("Close: expected errors.Is(err, errB)")
+	}
+}
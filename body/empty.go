@@ -88,6 +88,9 @@ func (body *emptyBody) ReadAt(p []byte, offset int64) (int, error) {
 	return 0, nil
 }
 
+// WriteTo writes nothing.  Like any io.WriterTo, it reports reaching the end
+// of the Body as a nil error rather than io.EOF, because io.Copy returns the
+// error from WriteTo verbatim.
 func (body *emptyBody) WriteTo(w io.Writer) (int64, error) {
 	body.mu.Lock()
 	defer body.mu.Unlock()
@@ -96,7 +99,7 @@ func (body *emptyBody) WriteTo(w io.Writer) (int64, error) {
 		return 0, fs.ErrClosed
 	}
 
-	return 0, io.EOF
+	return 0, nil
 }
 
 func (body *emptyBody) Copy() (Body, error) {
@@ -1,6 +1,8 @@
 package body
 
 import (
+	"bytes"
+	"io"
 	"testing"
 )
 
@@ -11,3 +13,17 @@ func TestEmptyBody(t *testing.T) {
 		EmptyBody: b,
 	})
 }
+
+func TestEmptyBody_WriteTo(t *testing.T) {
+	b := Empty()
+	defer b.Close()
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, b)
+	if err != nil {
+		t.Errorf("io.Copy: expected <nil>, got %s", formatAny(err))
+	}
+	if n != 0 {
+		t.Errorf("io.Copy: expected 0 bytes, got %d", n)
+	}
+}
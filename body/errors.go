@@ -1,7 +1,9 @@
 package body
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 )
 
 type UnknownWhenceSeekError struct {
@@ -45,3 +47,109 @@ func (err NegativeComputedOffsetSeekError) Error() string {
 }
 
 var _ error = NegativeComputedOffsetSeekError{}
+
+type MultiError struct {
+	Errors []error
+}
+
+func (err MultiError) GoString() string {
+	return fmt.Sprintf("MultiError{%#v}", err.Errors)
+}
+
+func (err MultiError) Error() string {
+	var buf strings.Builder
+	buf.WriteString("multiple errors: [")
+	for index, e := range err.Errors {
+		if index > 0 {
+			buf.WriteString("; ")
+		}
+		buf.WriteString(e.Error())
+	}
+	buf.WriteString("]")
+	return buf.String()
+}
+
+func (err MultiError) Is(target error) bool {
+	for _, e := range err.Errors {
+		if errors.Is(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (err MultiError) As(target interface{}) bool {
+	for _, e := range err.Errors {
+		if errors.As(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+var _ error = MultiError{}
+
+func joinErrors(list []error) error {
+	switch len(list) {
+	case 0:
+		return nil
+	case 1:
+		return list[0]
+	default:
+		return MultiError{Errors: list}
+	}
+}
+
+type SliceOutOfRangeError struct {
+	Offset int64
+	Length int64
+	Size   int64
+}
+
+func (err SliceOutOfRangeError) GoString() string {
+	return fmt.Sprintf("SliceOutOfRangeError{%d, %d, %d}", err.Offset, err.Length, err.Size)
+}
+
+func (err SliceOutOfRangeError) Error() string {
+	return fmt.Sprintf("Slice error: offset %d and length %d do not lie within a Body of %d bytes", err.Offset, err.Length, err.Size)
+}
+
+var _ error = SliceOutOfRangeError{}
+
+type NotSliceableError struct {
+	Type string
+}
+
+func (err NotSliceableError) GoString() string {
+	return fmt.Sprintf("NotSliceableError{%q}", err.Type)
+}
+
+func (err NotSliceableError) Error() string {
+	return fmt.Sprintf("Slice error: Body of type %s does not support slicing", err.Type)
+}
+
+var _ error = NotSliceableError{}
+
+// ErrBufferBudgetExceeded matches any BufferBudgetExceededError, via
+// errors.Is.
+var ErrBufferBudgetExceeded = errors.New("buffer budget exceeded")
+
+type BufferBudgetExceededError struct {
+	Requested int64
+	Used      int64
+	Limit     int64
+}
+
+func (err BufferBudgetExceededError) GoString() string {
+	return fmt.Sprintf("BufferBudgetExceededError{%d, %d, %d}", err.Requested, err.Used, err.Limit)
+}
+
+func (err BufferBudgetExceededError) Error() string {
+	return fmt.Sprintf("buffer budget exceeded: cannot buffer %d more bytes with %d of %d bytes already in use", err.Requested, err.Used, err.Limit)
+}
+
+func (err BufferBudgetExceededError) Is(target error) bool {
+	return target == ErrBufferBudgetExceeded
+}
+
+var _ error = BufferBudgetExceededError{}
@@ -0,0 +1,367 @@
+package body
+
+import (
+	"io"
+	"io/fs"
+	"sync"
+)
+
+// Pipe returns a new Body of unknown length, plus a PipeWriter which
+// produces its bytes.  It is typically used to stream a response which is
+// generated on the fly, such as NDJSON or chunked progress output.
+//
+// Each call to PipeWriter.Write blocks until the Body's consumer has read all
+// of the written bytes, or until the Body is closed.  Calling Close on the
+// Body causes all current and future writes to fail with io.ErrClosedPipe.
+//
+// PipeWriter.Flush inserts a flush point into the stream.  When the Body is
+// consumed via io.WriterTo, as Response.Serve does, the destination Writer is
+// flushed at each flush point if it has a "Flush()" method, e.g. if it is an
+// http.Flusher.  Readers that use Read see no flush points.
+//
+// The first call to Copy switches the Body and all of its copies over to
+// buffering the remaining bytes, as if by FromReader.  From then on, none of
+// them sees flush points, and reads may block until a full block of bytes has
+// been written or the PipeWriter is closed.  The PipeWriter sees the Body as
+// closed once the Body and all of its copies have been closed.
+//
+func Pipe() (Body, *PipeWriter) {
+	common := &pipeCommon{done: make(chan struct{})}
+	common.cond = sync.NewCond(&common.mu)
+	return &pipeBody{common: common}, &PipeWriter{common: common}
+}
+
+// FromFunc returns a new Body of unknown length whose bytes are produced by
+// the given function, as if by Pipe.
+//
+// The function runs in its own goroutine, which is started when the Body is
+// first read.  When the function returns, the PipeWriter is closed with its
+// return value, as if by CloseWithError.  If the Body is closed before it is
+// read, the function never runs.
+//
+func FromFunc(fn func(w *PipeWriter) error) Body {
+	b, w := Pipe()
+	b.(*pipeBody).common.start = func() {
+		go func() {
+			err := fn(w)
+			_ = w.CloseWithError(err)
+		}()
+	}
+	return b
+}
+
+type pipeCommon struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	start   func()
+	data    []byte
+	flush   bool
+	werr    error
+	rclosed bool
+	done    chan struct{}
+}
+
+// maybeStart runs the FromFunc goroutine, if any and not yet started.
+func (common *pipeCommon) maybeStart() {
+	if common.start != nil {
+		fn := common.start
+		common.start = nil
+		fn()
+	}
+}
+
+// PipeWriter is the producer half of a Body created by Pipe or FromFunc.
+// Its methods are safe for concurrent use.
+type PipeWriter struct {
+	wmu    sync.Mutex
+	common *pipeCommon
+}
+
+// Write writes p to the Body, blocking until the consumer has read all of it.
+// Returns io.ErrClosedPipe if either half of the pipe has been closed.
+func (w *PipeWriter) Write(p []byte) (int, error) {
+	w.wmu.Lock()
+	defer w.wmu.Unlock()
+
+	common := w.common
+	common.mu.Lock()
+	defer common.mu.Unlock()
+
+	for common.flush && !common.rclosed {
+		common.cond.Wait()
+	}
+	if common.rclosed || common.werr != nil {
+		return 0, io.ErrClosedPipe
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	common.data = p
+	common.cond.Broadcast()
+	for len(common.data) != 0 && !common.rclosed {
+		common.cond.Wait()
+	}
+
+	n := len(p) - len(common.data)
+	common.data = nil
+	if n < len(p) {
+		return n, io.ErrClosedPipe
+	}
+	return n, nil
+}
+
+// Flush inserts a flush point after all bytes written so far.  Returns
+// io.ErrClosedPipe if either half of the pipe has been closed.
+func (w *PipeWriter) Flush() error {
+	w.wmu.Lock()
+	defer w.wmu.Unlock()
+
+	common := w.common
+	common.mu.Lock()
+	defer common.mu.Unlock()
+
+	if common.rclosed || common.werr != nil {
+		return io.ErrClosedPipe
+	}
+
+	common.flush = true
+	common.cond.Broadcast()
+	return nil
+}
+
+// Close closes the PipeWriter.  The consumer reads io.EOF after consuming all
+// previously written bytes.
+func (w *PipeWriter) Close() error {
+	return w.CloseWithError(nil)
+}
+
+// CloseWithError closes the PipeWriter.  The consumer reads the given error
+// after consuming all previously written bytes; if err is nil, it reads
+// io.EOF instead.  Only the first call to Close or CloseWithError has any
+// effect.
+func (w *PipeWriter) CloseWithError(err error) error {
+	if err == nil {
+		err = io.EOF
+	}
+
+	common := w.common
+	common.mu.Lock()
+	defer common.mu.Unlock()
+
+	if common.werr == nil {
+		common.werr = err
+		common.cond.Broadcast()
+	}
+	return nil
+}
+
+// Done returns a channel which is closed when the consumer closes the Body.
+// Producers which block on other work can use it to abandon that work early.
+func (w *PipeWriter) Done() <-chan struct{} {
+	return w.common.done
+}
+
+var _ io.WriteCloser = (*PipeWriter)(nil)
+
+type pipeBody struct {
+	common   *pipeCommon
+	closed   bool
+	buffered Body
+}
+
+// promoted returns the buffered Body which took over after the first Copy, or
+// nil if the Body has never been copied.
+func (body *pipeBody) promoted() Body {
+	common := body.common
+	common.mu.Lock()
+	defer common.mu.Unlock()
+	return body.buffered
+}
+
+func (body *pipeBody) BytesRemaining() int64 {
+	if b := body.promoted(); b != nil {
+		return b.BytesRemaining()
+	}
+
+	common := body.common
+	common.mu.Lock()
+	defer common.mu.Unlock()
+
+	if body.closed {
+		return 0
+	}
+	if common.werr != nil && len(common.data) == 0 {
+		return 0
+	}
+	return -1
+}
+
+func (body *pipeBody) Read(p []byte) (int, error) {
+	if b := body.promoted(); b != nil {
+		return b.Read(p)
+	}
+
+	common := body.common
+	common.mu.Lock()
+	defer common.mu.Unlock()
+
+	if body.closed {
+		return 0, fs.ErrClosed
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	common.maybeStart()
+	for {
+		if body.closed {
+			return 0, fs.ErrClosed
+		}
+		if len(common.data) != 0 {
+			n := copy(p, common.data)
+			common.data = common.data[n:]
+			if len(common.data) == 0 {
+				common.cond.Broadcast()
+			}
+			return n, nil
+		}
+		if common.flush {
+			common.flush = false
+			common.cond.Broadcast()
+			continue
+		}
+		if common.werr != nil {
+			return 0, common.werr
+		}
+		common.cond.Wait()
+	}
+}
+
+// WriteTo copies the pipe's bytes to w as they are written, calling w's
+// "Flush()" method at each flush point, if it has one.
+func (body *pipeBody) WriteTo(w io.Writer) (int64, error) {
+	if b := body.promoted(); b != nil {
+		return b.(io.WriterTo).WriteTo(w)
+	}
+
+	flusher, _ := w.(interface{ Flush() })
+
+	common := body.common
+	common.mu.Lock()
+	defer common.mu.Unlock()
+
+	if body.closed {
+		return 0, fs.ErrClosed
+	}
+
+	common.maybeStart()
+
+	var total int64
+	for {
+		if body.closed {
+			return total, fs.ErrClosed
+		}
+
+		if len(common.data) != 0 {
+			chunk := common.data
+
+			common.mu.Unlock()
+			n, err := w.Write(chunk)
+			common.mu.Lock()
+
+			total += int64(n)
+			if !body.closed {
+				common.data = common.data[n:]
+				if len(common.data) == 0 {
+					common.cond.Broadcast()
+				}
+			}
+			if err == nil && n < len(chunk) {
+				err = io.ErrShortWrite
+			}
+			if err != nil {
+				return total, err
+			}
+			continue
+		}
+
+		if common.flush {
+			common.flush = false
+			common.cond.Broadcast()
+			if flusher != nil {
+				common.mu.Unlock()
+				flusher.Flush()
+				common.mu.Lock()
+			}
+			continue
+		}
+
+		if common.werr == io.EOF {
+			return total, nil
+		}
+		if common.werr != nil {
+			return total, common.werr
+		}
+		common.cond.Wait()
+	}
+}
+
+func (body *pipeBody) Close() error {
+	common := body.common
+	common.mu.Lock()
+
+	if body.closed {
+		common.mu.Unlock()
+		return fs.ErrClosed
+	}
+
+	body.closed = true
+	if b := body.buffered; b != nil {
+		// The buffered Body closes the pipe once its last copy is
+		// closed.
+		common.mu.Unlock()
+		return b.Close()
+	}
+
+	common.rclosed = true
+	common.start = nil
+	close(common.done)
+	common.cond.Broadcast()
+	common.mu.Unlock()
+	return nil
+}
+
+// Copy promotes the Body to a buffered Body on first use, reading from a
+// private pipeBody which shares the same pipe, and then copies that.
+func (body *pipeBody) Copy() (Body, error) {
+	common := body.common
+	common.mu.Lock()
+
+	if body.closed {
+		common.mu.Unlock()
+		return closedSingleton, nil
+	}
+
+	if body.buffered == nil {
+		b, err := FromReaderAndLength(&pipeBody{common: common}, -1)
+		if err != nil {
+			common.mu.Unlock()
+			return nil, err
+		}
+		body.buffered = b
+	}
+	b := body.buffered
+	common.mu.Unlock()
+
+	return b.Copy()
+}
+
+func (body *pipeBody) Unwrap() io.Reader {
+	return nil
+}
+
+var (
+	_ Body        = (*pipeBody)(nil)
+	_ io.WriterTo = (*pipeBody)(nil)
+)
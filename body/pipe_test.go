@@ -0,0 +1,295 @@
+package body
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+)
+
+type flushRecorder struct {
+	bytes.Buffer
+	flushes []string
+}
+
+func (w *flushRecorder) Flush() {
+	w.flushes = append(w.flushes, w.String())
+}
+
+func TestPipe_Read(t *testing.T) {
+	b, w := Pipe()
+	defer b.Close()
+
+	go func() {
+		_, _ = w.Write([]byte("abc"))
+		_ = w.Flush()
+		_, _ = w.Write([]byte("def"))
+		_ = w.Close()
+	}()
+
+	if expect, actual := int64(-1), b.BytesRemaining(); expect != actual {
+		t.Errorf("BytesRemaining: expected %d, got %d", expect, actual)
+	}
+
+	raw, err := io.ReadAll(b)
+	if err != nil {
+		t.Errorf("ReadAll failed: %v", err)
+	}
+	if expect, actual := "abcdef", string(raw); expect != actual {
+		t.Errorf("ReadAll: expected %q, got %q", expect, actual)
+	}
+	if expect, actual := int64(0), b.BytesRemaining(); expect != actual {
+		t.Errorf("BytesRemaining: expected %d, got %d", expect, actual)
+	}
+}
+
+func TestPipe_WriteTo(t *testing.T) {
+	b, w := Pipe()
+	defer b.Close()
+
+	go func() {
+		_, _ = w.Write([]byte("{\"n\":1}\n"))
+		_ = w.Flush()
+		_, _ = w.Write([]byte("{\"n\":2}\n"))
+		_, _ = w.Write([]byte("{\"n\":3}\n"))
+		_ = w.Flush()
+		_ = w.Close()
+	}()
+
+	var dst flushRecorder
+	n, err := b.(io.WriterTo).WriteTo(&dst)
+	if err != nil {
+		t.Errorf("WriteTo failed: %v", err)
+	}
+	if expect := int64(24); n != expect {
+		t.Errorf("WriteTo: expected %d, got %d", expect, n)
+	}
+
+	expect := []string{
+		"{\"n\":1}\n",
+		"{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n",
+	}
+	if len(dst.flushes) != len(expect) {
+		t.Fatalf("expected flushes %q, got %q", expect, dst.flushes)
+	}
+	for i := range expect {
+		if expect[i] != dst.flushes[i] {
+			t.Errorf("flush %d: expected %q, got %q", i, expect[i], dst.flushes[i])
+		}
+	}
+}
+
+func TestPipe_CloseWithError(t *testing.T) {
+	errTest := errors.New("producer failed")
+
+	b, w := Pipe()
+	defer b.Close()
+
+	go func() {
+		_, _ = w.Write([]byte("partial"))
+		_ = w.CloseWithError(errTest)
+	}()
+
+	raw, err := io.ReadAll(b)
+	if !errors.Is(err, errTest) {
+		t.Errorf("ReadAll: expected %v, got %v", errTest, err)
+	}
+	if expect, actual := "partial", string(raw); expect != actual {
+		t.Errorf("ReadAll: expected %q, got %q", expect, actual)
+	}
+
+	if _, err := w.Write([]byte("more")); err != io.ErrClosedPipe {
+		t.Errorf("Write after CloseWithError: expected io.ErrClosedPipe, got %v", err)
+	}
+}
+
+func TestPipe_ConsumerClose(t *testing.T) {
+	b, w := Pipe()
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := w.Write([]byte("never read"))
+		result <- err
+	}()
+
+	p := make([]byte, 5)
+	if _, err := io.ReadFull(b, p); err != nil {
+		t.Fatalf("ReadFull failed: %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if err := <-result; err != io.ErrClosedPipe {
+		t.Errorf("Write: expected io.ErrClosedPipe, got %v", err)
+	}
+
+	select {
+	case <-w.Done():
+	default:
+		t.Errorf("expected Done to be closed")
+	}
+
+	if err := b.Close(); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("Close: expected fs.ErrClosed, got %v", err)
+	}
+	if _, err := b.Read(p); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("Read: expected fs.ErrClosed, got %v", err)
+	}
+
+	if dupe, err := b.Copy(); err != nil {
+		t.Errorf("Copy failed: %v", err)
+	} else if _, err := dupe.Read(p); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("Read from copy: expected fs.ErrClosed, got %v", err)
+	}
+}
+
+func TestFromFunc(t *testing.T) {
+	b := FromFunc(func(w *PipeWriter) error {
+		for i := 0; i < 3; i++ {
+			if _, err := w.Write([]byte{'a' + byte(i)}); err != nil {
+				return err
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	defer b.Close()
+
+	var dst flushRecorder
+	if _, err := b.(io.WriterTo).WriteTo(&dst); err != nil {
+		t.Errorf("WriteTo failed: %v", err)
+	}
+	if expect, actual := "abc", dst.String(); expect != actual {
+		t.Errorf("WriteTo: expected %q, got %q", expect, actual)
+	}
+	if expect, actual := 3, len(dst.flushes); expect != actual {
+		t.Errorf("expected %d flushes, got %d", expect, actual)
+	}
+}
+
+func TestFromFunc_NotStartedIfClosed(t *testing.T) {
+	ran := make(chan struct{}, 1)
+	b := FromFunc(func(w *PipeWriter) error {
+		ran <- struct{}{}
+		return nil
+	})
+	if err := b.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+
+	select {
+	case <-ran:
+		t.Errorf("expected function not to run")
+	default:
+	}
+}
+
+func TestFromFunc_Error(t *testing.T) {
+	errTest := errors.New("generator failed")
+	b := FromFunc(func(w *PipeWriter) error {
+		_, _ = w.Write([]byte("x"))
+		return errTest
+	})
+	defer b.Close()
+
+	var dst bytes.Buffer
+	if _, err := b.(io.WriterTo).WriteTo(&dst); !errors.Is(err, errTest) {
+		t.Errorf("WriteTo: expected %v, got %v", errTest, err)
+	}
+	if expect, actual := "x", dst.String(); expect != actual {
+		t.Errorf("WriteTo: expected %q, got %q", expect, actual)
+	}
+}
+
+func TestPipe_Copy(t *testing.T) {
+	b := FromFunc(func(w *PipeWriter) error {
+		for _, chunk := range []string{"abc", "def"} {
+			if _, err := w.Write([]byte(chunk)); err != nil {
+				return err
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	dupe, err := b.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	for index, x := range []Body{b, dupe} {
+		raw, err := io.ReadAll(x)
+		if err != nil {
+			t.Errorf("%d: ReadAll failed: %v", index, err)
+		}
+		if expect, actual := "abcdef", string(raw); expect != actual {
+			t.Errorf("%d: ReadAll: expected %q, got %q", index, expect, actual)
+		}
+		if expect, actual := int64(0), x.BytesRemaining(); expect != actual {
+			t.Errorf("%d: BytesRemaining: expected %d, got %d", index, expect, actual)
+		}
+	}
+
+	if err := b.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if err := dupe.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
+
+func TestPipe_CopyCloses(t *testing.T) {
+	b, w := Pipe()
+
+	dupe, err := b.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	if err := b.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	select {
+	case <-w.Done():
+		t.Errorf("expected Done to remain open while a copy is open")
+	default:
+	}
+
+	if err := dupe.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	select {
+	case <-w.Done():
+	default:
+		t.Errorf("expected Done to be closed")
+	}
+}
+
+func TestPipe_ConcatCopy(t *testing.T) {
+	b := Concat(FromString("<"), FromFunc(func(w *PipeWriter) error {
+		_, err := w.Write([]byte("abc"))
+		return err
+	}), FromString(">"))
+	defer b.Close()
+
+	dupe, err := b.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	defer dupe.Close()
+
+	for index, x := range []Body{dupe, b} {
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, x); err != nil {
+			t.Errorf("%d: Copy failed: %v", index, err)
+		}
+		if expect, actual := "<abc>", buf.String(); expect != actual {
+			t.Errorf("%d: expected %q, got %q", index, expect, actual)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"os"
 	"sync"
 
 	"github.com/chronos-tachyon/assert"
@@ -13,6 +14,7 @@ type readerAtCommon struct {
 	mu     sync.Mutex
 	r      io.Reader
 	at     io.ReaderAt
+	spare  []*os.File
 	length int64
 	refcnt int32
 }
@@ -33,6 +35,10 @@ func (common *readerAtCommon) unref() error {
 		return nil
 	}
 
+	for _, g := range common.spare {
+		_ = g.Close()
+	}
+
 	var err error
 	if c, cOK := common.r.(io.Closer); cOK {
 		err = c.Close()
@@ -40,6 +46,7 @@ func (common *readerAtCommon) unref() error {
 
 	common.r = nil
 	common.at = nil
+	common.spare = nil
 	common.length = 0
 	common.refcnt = 0
 
@@ -61,6 +68,24 @@ func (common *readerAtCommon) readAt(p []byte, offset int64) (int, error) {
 	return n, err
 }
 
+func (common *readerAtCommon) sharedReadAt(p []byte, offset int64) (int, error) {
+	common.mu.Lock()
+	defer common.mu.Unlock()
+
+	if common.at == nil {
+		return 0, fs.ErrClosed
+	}
+
+	return common.readAt(p, offset)
+}
+
+func (common *readerAtCommon) reader() io.Reader {
+	common.mu.Lock()
+	r := common.r
+	common.mu.Unlock()
+	return r
+}
+
 type readerAtBody struct {
 	mu     sync.Mutex
 	common *readerAtCommon
@@ -234,6 +259,54 @@ func (body *readerAtBody) ReadAt(p []byte, offset int64) (int, error) {
 	return n, err
 }
 
+func (body *readerAtBody) WriteTo(w io.Writer) (int64, error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return 0, fs.ErrClosed
+	}
+
+	if body.err == io.EOF {
+		return 0, nil
+	}
+
+	if body.err != nil {
+		return 0, body.err
+	}
+
+	length := body.common.BytesRemaining()
+	if body.offset > length {
+		body.offset = length
+	}
+
+	n, readErr, writeErr := writeRangeTo(w, body.common, 0, body.offset, length)
+	body.offset += n
+	if writeErr != nil {
+		return n, writeErr
+	}
+	if readErr != nil {
+		body.truncate()
+		if readErr != io.EOF {
+			body.err = readErr
+			return n, readErr
+		}
+	}
+
+	body.err = io.EOF
+	return n, nil
+}
+
+// truncate records that the underlying Reader ended at the current offset.
+func (body *readerAtBody) truncate() {
+	common := body.common
+	common.mu.Lock()
+	if body.offset < common.length {
+		common.length = body.offset
+	}
+	common.mu.Unlock()
+}
+
 func (body *readerAtBody) Copy() (Body, error) {
 	body.mu.Lock()
 	defer body.mu.Unlock()
@@ -269,4 +342,5 @@ var (
 	_ Body        = (*readerAtBody)(nil)
 	_ io.Seeker   = (*readerAtBody)(nil)
 	_ io.ReaderAt = (*readerAtBody)(nil)
+	_ io.WriterTo = (*readerAtBody)(nil)
 )
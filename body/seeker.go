@@ -65,6 +65,24 @@ func (common *seekerCommon) readAt(p []byte, offset int64) (int, error) {
 	return n, err
 }
 
+func (common *seekerCommon) sharedReadAt(p []byte, offset int64) (int, error) {
+	common.mu.Lock()
+	defer common.mu.Unlock()
+
+	if common.s == nil {
+		return 0, fs.ErrClosed
+	}
+
+	return common.readAt(p, offset)
+}
+
+func (common *seekerCommon) reader() io.Reader {
+	common.mu.Lock()
+	defer common.mu.Unlock()
+
+	return common.s
+}
+
 type seekerBody struct {
 	mu     sync.Mutex
 	common *seekerCommon
@@ -0,0 +1,147 @@
+package body
+
+import (
+	"io"
+	"os"
+
+	"github.com/chronos-tachyon/assert"
+)
+
+// privateFile returns a handle on the regular *os.File that backs this
+// common, if any.  The handle has its own file offset, so that it can be
+// handed to sendfile(2) and friends, which read from the current offset,
+// without disturbing concurrent readers or holding common.mu.
+//
+// Handles are opened by openPrivate and kept for reuse by putPrivateFile
+// until the last copy is closed.  Returns nil if there is no *os.File, or if
+// it is not a regular file, or if openPrivate fails; callers then fall back to
+// copying through userspace.
+//
+func (common *readerAtCommon) privateFile() *os.File {
+	common.mu.Lock()
+	if n := len(common.spare); n > 0 {
+		g := common.spare[n-1]
+		common.spare[n-1] = nil
+		common.spare = common.spare[:n-1]
+		common.mu.Unlock()
+		return g
+	}
+	f, _ := common.at.(*os.File)
+	common.mu.Unlock()
+
+	if f == nil {
+		return nil
+	}
+
+	g, err := openPrivate(f)
+	if err != nil {
+		return nil
+	}
+
+	fi, err := g.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		_ = g.Close()
+		return nil
+	}
+
+	return g
+}
+
+// putPrivateFile returns a handle obtained from privateFile for reuse, or
+// closes it if the last copy has already been closed.
+func (common *readerAtCommon) putPrivateFile(g *os.File) {
+	common.mu.Lock()
+	if common.at != nil {
+		common.spare = append(common.spare, g)
+		g = nil
+	}
+	common.mu.Unlock()
+
+	if g != nil {
+		_ = g.Close()
+	}
+}
+
+// sendFile writes the given range of the underlying *os.File to w, if w
+// implements io.ReaderFrom.  The bytes are passed to ReadFrom as an
+// *io.LimitedReader over a private handle on the file, which is the shape
+// that net/http and os.File recognize in order to use sendfile(2), splice(2),
+// or copy_file_range(2) instead of copying through userspace.
+//
+// Returns ok=false, having done nothing, if neither w nor this common is
+// suitable.  Otherwise, returns io.EOF if the file ended early.
+//
+func (common *readerAtCommon) sendFile(w io.Writer, offset int64, length int64) (int64, bool, error) {
+	rf, ok := w.(io.ReaderFrom)
+	if !ok {
+		return 0, false, nil
+	}
+
+	f := common.privateFile()
+	if f == nil {
+		return 0, false, nil
+	}
+	defer common.putPrivateFile(f)
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, false, nil
+	}
+
+	n, err := rf.ReadFrom(&io.LimitedReader{R: f, N: length})
+	if err == nil && n < length {
+		err = io.EOF
+	}
+	return n, true, err
+}
+
+// writeRangeTo writes the bytes of source in [start+offset, start+length) to
+// w, using sendFile if possible.  Returns the number of bytes written, plus
+// either the error which stopped reading from source (io.EOF if it ended
+// early) or the error which stopped writing to w.
+//
+func writeRangeTo(w io.Writer, source sliceSource, start int64, offset int64, length int64) (total int64, readErr error, writeErr error) {
+	if offset >= length {
+		return 0, nil, nil
+	}
+
+	if common, ok := source.(*readerAtCommon); ok {
+		n, ok, err := common.sendFile(w, start+offset, length-offset)
+		if ok {
+			if err == io.EOF {
+				return n, err, nil
+			}
+			return n, nil, err
+		}
+	}
+
+	bufLen := length - offset
+	if bufLen > blockSize {
+		bufLen = blockSize
+	}
+	buf := make([]byte, bufLen)
+
+	for offset < length {
+		x := int64(len(buf))
+		if avail := length - offset; x > avail {
+			x = avail
+		}
+
+		n, err := source.sharedReadAt(buf[0:x], start+offset)
+		if n > 0 {
+			m, err2 := w.Write(buf[0:n])
+			assert.Assertf(m >= 0, "Write must return %d >= 0", m)
+			assert.Assertf(m <= n, "Write must return %d <= %d", m, n)
+			total += int64(m)
+			offset += int64(m)
+			if err2 != nil {
+				return total, nil, err2
+			}
+		}
+
+		if err != nil {
+			return total, err, nil
+		}
+	}
+
+	return total, nil, nil
+}
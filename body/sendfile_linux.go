@@ -0,0 +1,37 @@
+//go:build linux
+// +build linux
+
+package body
+
+import (
+	"os"
+	"strconv"
+)
+
+// openPrivate opens a new file description for f via /proc/self/fd, so that
+// the new handle has its own file offset.  Unlike opening f.Name() again, this
+// works for relative names after a chdir and for files which have since been
+// renamed or unlinked.  It fails if /proc is not mounted.
+//
+func openPrivate(f *os.File) (*os.File, error) {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var g *os.File
+	var openErr error
+	err = rc.Control(func(fd uintptr) {
+		g, openErr = os.Open("/proc/self/fd/" + strconv.FormatUint(uint64(fd), 10))
+	})
+	if err == nil {
+		err = openErr
+	}
+	if err != nil {
+		if g != nil {
+			_ = g.Close()
+		}
+		return nil, err
+	}
+	return g, nil
+}
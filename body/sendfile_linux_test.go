@@ -0,0 +1,102 @@
+//go:build linux
+// +build linux
+
+package body
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeToRecorderForTest(t *testing.T, b Body, data []byte) {
+	t.Helper()
+
+	var w readerFromRecorder
+	if _, err := b.(io.WriterTo).WriteTo(&w); err != nil {
+		t.Errorf("WriteTo failed: %v", err)
+	}
+	if expect, actual := 1, w.fileCalls; expect != actual {
+		t.Errorf("expected %d ReadFrom calls with a file, got %d", expect, actual)
+	}
+	if !bytes.Equal(w.Bytes(), data) {
+		t.Errorf("WriteTo: data mismatch")
+	}
+}
+
+func TestReaderAtBody_SendFileRelativePath(t *testing.T) {
+	data := sendFileDataForTest()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "input.bin"), data, 0666); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd failed: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Chdir failed: %v", err)
+	}
+	f, err := os.Open("input.bin")
+	if err2 := os.Chdir(wd); err2 != nil {
+		t.Fatalf("Chdir failed: %v", err2)
+	}
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	b, err := FromReader(f)
+	if err != nil {
+		t.Fatalf("FromReader failed: %v", err)
+	}
+	defer b.Close()
+
+	writeToRecorderForTest(t, b, data)
+}
+
+func TestReaderAtBody_SendFileUnlinked(t *testing.T) {
+	data := sendFileDataForTest()
+
+	f := openFileForTest(t, data)
+	if err := os.Remove(f.Name()); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+
+	b, err := FromReader(f)
+	if err != nil {
+		t.Fatalf("FromReader failed: %v", err)
+	}
+	defer b.Close()
+
+	writeToRecorderForTest(t, b, data)
+}
+
+func TestReaderAtBody_SendFileReusesHandle(t *testing.T) {
+	data := sendFileDataForTest()
+
+	b, err := FromReader(openFileForTest(t, data))
+	if err != nil {
+		t.Fatalf("FromReader failed: %v", err)
+	}
+
+	dupe, err := b.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	common := b.(*readerAtBody).common
+	writeToRecorderForTest(t, b, data)
+	writeToRecorderForTest(t, dupe, data)
+	if expect, actual := 1, len(common.spare); expect != actual {
+		t.Errorf("expected %d spare handle, got %d", expect, actual)
+	}
+
+	_ = b.Close()
+	_ = dupe.Close()
+	if common.spare != nil {
+		t.Errorf("expected spare handles to be closed, got %v", common.spare)
+	}
+}
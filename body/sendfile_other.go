@@ -0,0 +1,36 @@
+//go:build !linux
+// +build !linux
+
+package body
+
+import (
+	"fmt"
+	"os"
+)
+
+// openPrivate opens f.Name() again, so that the new handle has its own file
+// offset, and checks that the name still refers to the same file.  This fails
+// for relative names after a chdir and for files which have since been
+// renamed or unlinked, in which case WriteTo copies through userspace.
+//
+func openPrivate(f *os.File) (*os.File, error) {
+	fi1, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := os.Open(f.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	fi2, err := g.Stat()
+	if err == nil && !os.SameFile(fi1, fi2) {
+		err = fmt.Errorf("%q no longer refers to the same file", f.Name())
+	}
+	if err != nil {
+		_ = g.Close()
+		return nil, err
+	}
+	return g, nil
+}
@@ -0,0 +1,268 @@
+package body
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readerFromRecorder is an io.ReaderFrom which records whether it was handed
+// an *io.LimitedReader over an *os.File, i.e. a reader that net/http can send
+// with sendfile(2).
+type readerFromRecorder struct {
+	bytes.Buffer
+	calls     int
+	fileCalls int
+}
+
+func (w *readerFromRecorder) ReadFrom(r io.Reader) (int64, error) {
+	w.calls++
+	if lr, ok := r.(*io.LimitedReader); ok {
+		if _, ok := lr.R.(*os.File); ok {
+			w.fileCalls++
+		}
+	}
+	return w.Buffer.ReadFrom(r)
+}
+
+func openFileForTest(t *testing.T, data []byte) *os.File {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "input.bin")
+	if err := os.WriteFile(path, data, 0666); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	return f
+}
+
+func sendFileDataForTest() []byte {
+	data := make([]byte, 3*blockSize+17)
+	for i := range data {
+		data[i] = byte(i * 11)
+	}
+	return data
+}
+
+func TestReaderAtBody_SendFile(t *testing.T) {
+	data := sendFileDataForTest()
+
+	b, err := FromReader(openFileForTest(t, data))
+	if err != nil {
+		t.Fatalf("FromReader failed: %v", err)
+	}
+	defer b.Close()
+
+	head := make([]byte, 10)
+	if _, err := io.ReadFull(b, head); err != nil {
+		t.Fatalf("ReadFull failed: %v", err)
+	}
+
+	// sendfile reads through a private handle, so a copy reading via ReadAt
+	// is unaffected.
+	dupe, err := b.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	defer dupe.Close()
+
+	var w readerFromRecorder
+	n, err := b.(io.WriterTo).WriteTo(&w)
+	if err != nil {
+		t.Errorf("WriteTo failed: %v", err)
+	}
+	if expect := int64(len(data) - len(head)); n != expect {
+		t.Errorf("WriteTo: expected %d bytes, got %d", expect, n)
+	}
+	if !bytes.Equal(w.Bytes(), data[len(head):]) {
+		t.Errorf("WriteTo: data mismatch")
+	}
+	if expect, actual := 1, w.fileCalls; expect != actual {
+		t.Errorf("WriteTo: expected %d ReadFrom calls with a file, got %d of %d", expect, actual, w.calls)
+	}
+	if expect, actual := int64(0), b.BytesRemaining(); expect != actual {
+		t.Errorf("BytesRemaining: expected %d, got %d", expect, actual)
+	}
+
+	raw, err := io.ReadAll(dupe)
+	if err != nil {
+		t.Errorf("ReadAll failed: %v", err)
+	}
+	if !bytes.Equal(raw, data[len(head):]) {
+		t.Errorf("ReadAll: copy does not match input")
+	}
+}
+
+func TestReaderAtBody_SendFileFallback(t *testing.T) {
+	data := sendFileDataForTest()
+
+	b, err := FromReader(openFileForTest(t, data))
+	if err != nil {
+		t.Fatalf("FromReader failed: %v", err)
+	}
+	defer b.Close()
+
+	// A plain io.Writer gets a userspace copy.
+	var w bytes.Buffer
+	if _, err := b.(io.WriterTo).WriteTo(struct{ io.Writer }{&w}); err != nil {
+		t.Errorf("WriteTo failed: %v", err)
+	}
+	if !bytes.Equal(w.Bytes(), data) {
+		t.Errorf("WriteTo: data mismatch")
+	}
+}
+
+func TestReaderAtBody_SendFileTruncated(t *testing.T) {
+	data := sendFileDataForTest()
+
+	f := openFileForTest(t, data)
+	b, err := FromReader(f)
+	if err != nil {
+		t.Fatalf("FromReader failed: %v", err)
+	}
+	defer b.Close()
+
+	if err := os.Truncate(f.Name(), 100); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+
+	var w readerFromRecorder
+	n, err := b.(io.WriterTo).WriteTo(&w)
+	if err != nil {
+		t.Errorf("WriteTo failed: %v", err)
+	}
+	if expect := int64(100); n != expect {
+		t.Errorf("WriteTo: expected %d bytes, got %d", expect, n)
+	}
+
+	// The Body now knows that the file is shorter.
+	offset, err := b.(io.Seeker).Seek(0, io.SeekEnd)
+	if expect := int64(100); err != nil || offset != expect {
+		t.Errorf("Seek: expected (%d, <nil>), got (%d, %s)", expect, offset, formatAny(err))
+	}
+}
+
+func TestSliceBody_SendFile(t *testing.T) {
+	data := sendFileDataForTest()
+
+	b, err := FromReader(openFileForTest(t, data))
+	if err != nil {
+		t.Fatalf("FromReader failed: %v", err)
+	}
+	defer b.Close()
+
+	outer, err := Slice(b, 1000, int64(len(data)-2000))
+	if err != nil {
+		t.Fatalf("Slice failed: %v", err)
+	}
+	defer outer.Close()
+
+	inner, err := Slice(outer, 500, 2*blockSize)
+	if err != nil {
+		t.Fatalf("Slice failed: %v", err)
+	}
+	defer inner.Close()
+
+	var w readerFromRecorder
+	n, err := inner.(io.WriterTo).WriteTo(&w)
+	if err != nil {
+		t.Errorf("WriteTo failed: %v", err)
+	}
+	if expect := int64(2 * blockSize); n != expect {
+		t.Errorf("WriteTo: expected %d bytes, got %d", expect, n)
+	}
+	if !bytes.Equal(w.Bytes(), data[1500:1500+2*blockSize]) {
+		t.Errorf("WriteTo: data mismatch")
+	}
+	if expect, actual := 1, w.fileCalls; expect != actual {
+		t.Errorf("WriteTo: expected %d ReadFrom calls with a file, got %d of %d", expect, actual, w.calls)
+	}
+
+	// Writing to another file exercises os.File's own ReadFrom.
+	out, err := os.Create(filepath.Join(t.TempDir(), "output.bin"))
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer out.Close()
+
+	if _, err := outer.(io.WriterTo).WriteTo(out); err != nil {
+		t.Errorf("WriteTo failed: %v", err)
+	}
+	raw, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !bytes.Equal(raw, data[1000:len(data)-1000]) {
+		t.Errorf("WriteTo: output file does not match input")
+	}
+}
+
+// blockingReaderFrom is an io.ReaderFrom which, like a slow client, does not
+// finish ReadFrom until it is released.
+type blockingReaderFrom struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingReaderFrom) ReadFrom(r io.Reader) (int64, error) {
+	close(w.started)
+	<-w.release
+	return io.Copy(io.Discard, r)
+}
+
+func (w *blockingReaderFrom) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func TestReaderAtBody_SendFileDoesNotBlockCopies(t *testing.T) {
+	data := sendFileDataForTest()
+
+	b, err := FromReader(openFileForTest(t, data))
+	if err != nil {
+		t.Fatalf("FromReader failed: %v", err)
+	}
+	defer b.Close()
+
+	dupe, err := b.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	defer dupe.Close()
+
+	w := &blockingReaderFrom{started: make(chan struct{}), release: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := b.(io.WriterTo).WriteTo(w); err != nil {
+			t.Errorf("WriteTo failed: %v", err)
+		}
+	}()
+	<-w.started
+
+	result := make(chan error, 1)
+	go func() {
+		p := make([]byte, 10)
+		_, err := dupe.(io.ReaderAt).ReadAt(p, 100)
+		if err == nil && !bytes.Equal(p, data[100:110]) {
+			t.Errorf("ReadAt: data mismatch")
+		}
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("ReadAt failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("ReadAt blocked behind an in-progress WriteTo")
+	}
+
+	close(w.release)
+	<-done
+}
@@ -0,0 +1,452 @@
+package body
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+
+	"github.com/chronos-tachyon/assert"
+)
+
+type sliceSource interface {
+	ref()
+	unref() error
+	BytesRemaining() int64
+	sharedReadAt(p []byte, offset int64) (int, error)
+	reader() io.Reader
+}
+
+var (
+	_ sliceSource = (*readerAtCommon)(nil)
+	_ sliceSource = (*seekerCommon)(nil)
+	_ sliceSource = (*copiedCommon)(nil)
+)
+
+type copiedCommon struct {
+	mu     sync.Mutex
+	b      Body
+	at     io.ReaderAt
+	length int64
+	refcnt int32
+}
+
+func (common *copiedCommon) ref() {
+	common.mu.Lock()
+	defer common.mu.Unlock()
+
+	common.refcnt++
+}
+
+func (common *copiedCommon) unref() error {
+	common.mu.Lock()
+	defer common.mu.Unlock()
+
+	common.refcnt--
+
+	if common.refcnt > 0 {
+		return nil
+	}
+
+	err := common.b.Close()
+
+	common.b = nil
+	common.at = nil
+	common.length = 0
+	common.refcnt = 0
+
+	return err
+}
+
+func (common *copiedCommon) BytesRemaining() int64 {
+	common.mu.Lock()
+	defer common.mu.Unlock()
+
+	return common.length
+}
+
+func (common *copiedCommon) sharedReadAt(p []byte, offset int64) (int, error) {
+	common.mu.Lock()
+	defer common.mu.Unlock()
+
+	if common.at == nil {
+		return 0, fs.ErrClosed
+	}
+
+	x := len(p)
+	n, err := common.at.ReadAt(p, offset)
+	assert.Assertf(n >= 0, "ReadAt must return %d >= 0", n)
+	assert.Assertf(n <= x, "ReadAt must return %d <= %d", n, x)
+	return n, err
+}
+
+func (common *copiedCommon) reader() io.Reader {
+	common.mu.Lock()
+	defer common.mu.Unlock()
+
+	if common.b == nil {
+		return nil
+	}
+	return common.b.Unwrap()
+}
+
+type sliceBody struct {
+	mu     sync.Mutex
+	source sliceSource
+	start  int64
+	length int64
+	err    error
+	offset int64
+	closed bool
+}
+
+func (body *sliceBody) size() int64 {
+	length := body.length
+	if avail := body.source.BytesRemaining() - body.start; avail < length {
+		length = avail
+	}
+	if length < 0 {
+		length = 0
+	}
+	return length
+}
+
+func (body *sliceBody) BytesRemaining() int64 {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return 0
+	}
+
+	length := body.size()
+	offset := body.offset
+	if offset >= length {
+		return 0
+	}
+	return (length - offset)
+}
+
+func (body *sliceBody) Read(p []byte) (int, error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return 0, fs.ErrClosed
+	}
+
+	if body.err != nil {
+		return 0, body.err
+	}
+
+	offset := body.offset
+	length := body.size()
+	if offset > length {
+		offset = length
+	}
+
+	avail := (length - offset)
+	x := int64(len(p))
+	eof := false
+	if x > avail {
+		x = avail
+		eof = true
+	}
+
+	var n int
+	var err error
+	if avail > 0 {
+		n, err = body.source.sharedReadAt(p[0:x], body.start+offset)
+	}
+	if eof && err == nil {
+		err = io.EOF
+	}
+
+	body.offset = offset + int64(n)
+
+	if err != nil {
+		body.err = err
+	}
+
+	return n, err
+}
+
+func (body *sliceBody) Close() error {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return fs.ErrClosed
+	}
+
+	source := body.source
+	body.source = nil
+	body.err = nil
+	body.offset = 0
+	body.closed = true
+	return source.unref()
+}
+
+func (body *sliceBody) Seek(offset int64, whence int) (int64, error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return -1, fs.ErrClosed
+	}
+
+	length := body.size()
+
+	switch whence {
+	case io.SeekStart:
+		if offset < 0 {
+			return -1, NegativeStartOffsetSeekError{offset}
+		}
+	case io.SeekCurrent:
+		offset += body.offset
+	case io.SeekEnd:
+		offset += length
+	default:
+		return -1, UnknownWhenceSeekError{whence}
+	}
+
+	if offset < 0 {
+		return -1, NegativeComputedOffsetSeekError{offset}
+	}
+
+	if offset > length {
+		offset = length
+	}
+
+	body.err = nil
+	body.offset = offset
+	return offset, nil
+}
+
+func (body *sliceBody) ReadAt(p []byte, offset int64) (int, error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return 0, fs.ErrClosed
+	}
+
+	if offset < 0 {
+		return 0, fmt.Errorf("ReadAt error: offset %d is negative", offset)
+	}
+
+	length := body.size()
+	if offset > length {
+		offset = length
+	}
+
+	avail := (length - offset)
+	x := int64(len(p))
+	eof := false
+	if x > avail {
+		x = avail
+		eof = true
+	}
+
+	var n int
+	var err error
+	if avail > 0 {
+		n, err = body.source.sharedReadAt(p[0:x], body.start+offset)
+	}
+	if eof && err == nil {
+		err = io.EOF
+	}
+
+	return n, err
+}
+
+func (body *sliceBody) WriteTo(w io.Writer) (int64, error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return 0, fs.ErrClosed
+	}
+
+	if body.err == io.EOF {
+		return 0, nil
+	}
+
+	if body.err != nil {
+		return 0, body.err
+	}
+
+	n, readErr, writeErr := writeRangeTo(w, body.source, body.start, body.offset, body.size())
+	body.offset += n
+	if writeErr != nil {
+		return n, writeErr
+	}
+	if readErr != nil && readErr != io.EOF {
+		body.err = readErr
+		return n, readErr
+	}
+
+	body.err = io.EOF
+	return n, nil
+}
+
+func (body *sliceBody) Copy() (Body, error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return closedSingleton, nil
+	}
+
+	dupe := &sliceBody{
+		source: body.source,
+		start:  body.start,
+		length: body.length,
+		err:    body.err,
+		offset: body.offset,
+		closed: body.closed,
+	}
+	dupe.source.ref()
+	return dupe, nil
+}
+
+func (body *sliceBody) Unwrap() io.Reader {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+
+	if body.closed {
+		return nil
+	}
+
+	return body.source.reader()
+}
+
+var (
+	_ Body        = (*sliceBody)(nil)
+	_ io.Seeker   = (*sliceBody)(nil)
+	_ io.ReaderAt = (*sliceBody)(nil)
+	_ io.WriterTo = (*sliceBody)(nil)
+)
+
+func checkSliceRange(offset, length, size int64) error {
+	if offset < 0 || length < 0 || offset > size || length > (size-offset) {
+		return SliceOutOfRangeError{Offset: offset, Length: length, Size: size}
+	}
+	return nil
+}
+
+func newSliceBody(source sliceSource, start, offset, length int64) (Body, error) {
+	if err := checkSliceRange(offset, length, source.BytesRemaining()-start); err != nil {
+		return nil, err
+	}
+
+	body := &sliceBody{
+		source: source,
+		start:  start + offset,
+		length: length,
+	}
+	source.ref()
+	return body, nil
+}
+
+func sliceImpl(b Body, offset, length int64) (Body, error) {
+	switch x := b.(type) {
+	case *closedBody:
+		return nil, fs.ErrClosed
+
+	case *emptyBody:
+		x.mu.Lock()
+		defer x.mu.Unlock()
+
+		if x.closed {
+			return nil, fs.ErrClosed
+		}
+		if err := checkSliceRange(offset, length, 0); err != nil {
+			return nil, err
+		}
+		return Empty(), nil
+
+	case *bytesBody:
+		x.mu.Lock()
+		defer x.mu.Unlock()
+
+		if x.closed {
+			return nil, fs.ErrClosed
+		}
+		if err := checkSliceRange(offset, length, int64(len(x.data))); err != nil {
+			return nil, err
+		}
+		i := offset
+		j := offset + length
+		return FromBytes(x.data[i:j]), nil
+
+	case *readerAtBody:
+		x.mu.Lock()
+		defer x.mu.Unlock()
+
+		if x.closed {
+			return nil, fs.ErrClosed
+		}
+		return newSliceBody(x.common, 0, offset, length)
+
+	case *seekerBody:
+		x.mu.Lock()
+		defer x.mu.Unlock()
+
+		if x.closed {
+			return nil, fs.ErrClosed
+		}
+		return newSliceBody(x.common, 0, offset, length)
+
+	case *sliceBody:
+		x.mu.Lock()
+		defer x.mu.Unlock()
+
+		if x.closed {
+			return nil, fs.ErrClosed
+		}
+		if err := checkSliceRange(offset, length, x.size()); err != nil {
+			return nil, err
+		}
+		return newSliceBody(x.source, x.start, offset, length)
+	}
+
+	if _, ok := b.(io.ReaderAt); !ok {
+		return nil, NotSliceableError{Type: fmt.Sprintf("%T", b)}
+	}
+
+	size := b.BytesRemaining()
+	if size < 0 {
+		return nil, NotSliceableError{Type: fmt.Sprintf("%T", b)}
+	}
+
+	if s, ok := b.(io.Seeker); ok {
+		cursor, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		size += cursor
+	}
+
+	if err := checkSliceRange(offset, length, size); err != nil {
+		return nil, err
+	}
+
+	dupe, err := b.Copy()
+	if err != nil {
+		return nil, err
+	}
+
+	at, ok := dupe.(io.ReaderAt)
+	if !ok {
+		_ = dupe.Close()
+		return nil, NotSliceableError{Type: fmt.Sprintf("%T", dupe)}
+	}
+
+	common := &copiedCommon{
+		b:      dupe,
+		at:     at,
+		length: size,
+	}
+	return newSliceBody(common, 0, offset, length)
+}
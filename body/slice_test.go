@@ -0,0 +1,164 @@
+package body
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/chronos-tachyon/morehttp/internal/mockreader"
+)
+
+func TestSliceBody_Bytes(t *testing.T) {
+	b0, err := Slice(FromString("xyz"), 1, 0)
+	if err != nil {
+		t.Errorf("Slice failed: %v", err)
+		return
+	}
+
+	b1, err := Slice(FromString("xxabcdyy"), 2, 4)
+	if err != nil {
+		t.Errorf("Slice failed: %v", err)
+		return
+	}
+
+	RunBodyTests(t, &TestOptions{
+		EmptyBody: b0,
+		ShortBody: b1,
+	})
+}
+
+func TestSliceBody_ReaderAt(t *testing.T) {
+	fi := &mockreader.FileInfo{NameValue: "input.txt", SizeValue: 8, ModeValue: 0444}
+	r := mockreader.New(
+		mockreader.ExpectStat(fi, nil),
+		mockreader.ExpectMark("ShortBody-Begin"),
+		mockreader.ExpectMark("Read-Begin"),
+		mockreader.ExpectReadAt(nil, 2, 0, nil),
+		mockreader.ExpectReadAt([]byte{'a'}, 2, 1, nil),
+		mockreader.ExpectReadAt([]byte{'b'}, 3, 1, nil),
+		mockreader.ExpectReadAt([]byte{'c'}, 4, 1, nil),
+		mockreader.ExpectReadAt([]byte{'d'}, 5, 1, nil),
+		mockreader.ExpectMark("Read-End"),
+		mockreader.ExpectMark("Seek-Begin"),
+		mockreader.ExpectReadAt([]byte{'a'}, 2, 1, nil),
+		mockreader.ExpectReadAt([]byte{'c', 'd'}, 4, 2, nil),
+		mockreader.ExpectMark("Seek-End"),
+		mockreader.ExpectMark("ReadAt-Begin"),
+		mockreader.ExpectReadAt([]byte{'a'}, 2, 1, nil),
+		mockreader.ExpectReadAt([]byte{'c', 'd'}, 4, 2, nil),
+		mockreader.ExpectMark("ReadAt-End"),
+		mockreader.ExpectMark("Close-Begin"),
+		mockreader.ExpectClose(nil),
+		mockreader.ExpectMark("Close-End"),
+		mockreader.ExpectMark("AfterClose-Begin"),
+		mockreader.ExpectMark("AfterClose-End"),
+		mockreader.ExpectMark("ShortBody-End"),
+	)
+
+	parent, err := FromReader(mockreader.Wrapper101{Inner: r})
+	if err != nil {
+		t.Errorf("FromReader failed: %v", err)
+		return
+	}
+
+	b, err := Slice(parent, 2, 4)
+	if err != nil {
+		t.Errorf("Slice failed: %v", err)
+		return
+	}
+
+	// The slice holds its own reference, so closing the parent must not
+	// close the underlying Reader.
+	if err := parent.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+		return
+	}
+
+	RunBodyTests(t, &TestOptions{
+		ShortMock: r,
+		ShortBody: b,
+	})
+}
+
+func TestSliceBody_Concat(t *testing.T) {
+	b, err := Slice(Concat(FromString("xab"), FromString("cdy")), 1, 4)
+	if err != nil {
+		t.Errorf("Slice failed: %v", err)
+		return
+	}
+
+	RunBodyTests(t, &TestOptions{
+		ShortBody: b,
+	})
+}
+
+func TestSliceBody_WriteTo(t *testing.T) {
+	parent, err := FromReader(strings.NewReader("0123456789"))
+	if err != nil {
+		t.Fatalf("FromReader failed: %v", err)
+	}
+	defer parent.Close()
+
+	outer, err := Slice(parent, 2, 6)
+	if err != nil {
+		t.Fatalf("Slice failed: %v", err)
+	}
+	defer outer.Close()
+
+	inner, err := Slice(outer, 1, 4)
+	if err != nil {
+		t.Fatalf("Slice failed: %v", err)
+	}
+	defer inner.Close()
+
+	var buf bytes.Buffer
+	n, err := inner.(io.WriterTo).WriteTo(&buf)
+	if err != nil {
+		t.Errorf("WriteTo failed: %v", err)
+	}
+	if expect := int64(4); n != expect {
+		t.Errorf("WriteTo: expected %d, got %d", expect, n)
+	}
+	if expect, actual := "3456", buf.String(); expect != actual {
+		t.Errorf("WriteTo: expected %q, got %q", expect, actual)
+	}
+	if expect, actual := int64(0), inner.BytesRemaining(); expect != actual {
+		t.Errorf("BytesRemaining: expected %d, got %d", expect, actual)
+	}
+	if expect, actual := int64(6), outer.BytesRemaining(); expect != actual {
+		t.Errorf("BytesRemaining: expected %d, got %d", expect, actual)
+	}
+}
+
+func TestSlice_Errors(t *testing.T) {
+	var xerr SliceOutOfRangeError
+
+	_, err := Slice(FromString("abcd"), 2, 3)
+	if !errors.As(err, &xerr) {
+		t.Errorf("Slice: expected SliceOutOfRangeError, got %s", formatAny(err))
+	}
+
+	_, err = Slice(FromString("abcd"), -1, 1)
+	if !errors.As(err, &xerr) {
+		t.Errorf("Slice: expected SliceOutOfRangeError, got %s", formatAny(err))
+	}
+
+	_, err = Slice(AlreadyClosed(), 0, 0)
+	if !isErrClosed(err) {
+		t.Errorf("Slice: expected fs.ErrClosed, got %s", formatAny(err))
+	}
+
+	buffered, err := FromReader(io.MultiReader(strings.NewReader("abcd")))
+	if err != nil {
+		t.Fatalf("FromReader failed: %v", err)
+	}
+	defer buffered.Close()
+
+	var notSliceable NotSliceableError
+	_, err = Slice(buffered, 0, 1)
+	if !errors.As(err, &notSliceable) {
+		t.Errorf("Slice: expected NotSliceableError, got %s", formatAny(err))
+	}
+}
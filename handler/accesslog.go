@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AccessLogEntry describes one request served by an Adaptor.
+type AccessLogEntry struct {
+	Time       time.Time
+	Method     string
+	URL        string
+	Proto      string
+	Host       string
+	Status     int
+	RecvBytes  int64
+	SendBytes  int64
+	Latency    time.Duration
+	RemoteAddr string
+	User       string
+	UserAgent  string
+	Referer    string
+	RequestID  string
+	Route      string
+	Err        error
+}
+
+func newAccessLogEntry(req *http.Request, startTime time.Time) *AccessLogEntry {
+	user, _, _ := req.BasicAuth()
+	return &AccessLogEntry{
+		Time:       startTime,
+		Method:     req.Method,
+		URL:        req.RequestURI,
+		Proto:      req.Proto,
+		Host:       req.Host,
+		RemoteAddr: req.RemoteAddr,
+		User:       user,
+		UserAgent:  req.UserAgent(),
+		Referer:    req.Referer(),
+	}
+}
+
+// AccessLogger receives an AccessLogEntry for each request served by an
+// Adaptor.  Implementations must not retain the entry after returning, and
+// should return quickly.
+type AccessLogger interface {
+	LogAccess(entry *AccessLogEntry)
+}
+
+// AccessLogFormat renders an AccessLogEntry as a single line, including the
+// trailing newline, appending it to buf.
+type AccessLogFormat func(buf []byte, entry *AccessLogEntry) []byte
+
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// CommonLogFormat renders entries in the Apache Common Log Format.
+func CommonLogFormat(buf []byte, entry *AccessLogEntry) []byte {
+	buf = appendCommon(buf, entry)
+	return append(buf, '\n')
+}
+
+// CombinedLogFormat renders entries in the Apache Combined Log Format.
+func CombinedLogFormat(buf []byte, entry *AccessLogEntry) []byte {
+	buf = appendCommon(buf, entry)
+	buf = append(buf, ' ')
+	buf = appendQuoted(buf, entry.Referer)
+	buf = append(buf, ' ')
+	buf = appendQuoted(buf, entry.UserAgent)
+	return append(buf, '\n')
+}
+
+func appendCommon(buf []byte, entry *AccessLogEntry) []byte {
+	host := entry.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	buf = appendDash(buf, host)
+	buf = append(buf, " - "...)
+	buf = appendDash(buf, entry.User)
+	buf = append(buf, " ["...)
+	buf = entry.Time.AppendFormat(buf, clfTimeFormat)
+	buf = append(buf, "] "...)
+	buf = appendQuoted(buf, entry.Method+" "+entry.URL+" "+entry.Proto)
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, int64(entry.Status), 10)
+	buf = append(buf, ' ')
+	if entry.SendBytes == 0 {
+		buf = append(buf, '-')
+	} else {
+		buf = strconv.AppendInt(buf, entry.SendBytes, 10)
+	}
+	return buf
+}
+
+func appendDash(buf []byte, str string) []byte {
+	if str == "" {
+		return append(buf, '-')
+	}
+	return appendEscaped(buf, str)
+}
+
+func appendQuoted(buf []byte, str string) []byte {
+	buf = append(buf, '"')
+	buf = appendEscaped(buf, str)
+	return append(buf, '"')
+}
+
+func appendEscaped(buf []byte, str string) []byte {
+	const hexDigits = "0123456789abcdef"
+	for i := 0; i < len(str); i++ {
+		ch := str[i]
+		switch {
+		case ch == '"' || ch == '\\':
+			buf = append(buf, '\\', ch)
+		case ch < 0x20 || ch >= 0x7f:
+			buf = append(buf, '\\', 'x', hexDigits[ch>>4], hexDigits[ch&0xf])
+		default:
+			buf = append(buf, ch)
+		}
+	}
+	return buf
+}
+
+type jsonAccessLogEntry struct {
+	Time       string  `json:"time"`
+	Method     string  `json:"method"`
+	URL        string  `json:"url"`
+	Proto      string  `json:"proto"`
+	Host       string  `json:"host,omitempty"`
+	Status     int     `json:"status"`
+	RecvBytes  int64   `json:"recv_bytes"`
+	SendBytes  int64   `json:"send_bytes"`
+	Latency    float64 `json:"latency_seconds"`
+	RemoteAddr string  `json:"remote_addr,omitempty"`
+	User       string  `json:"user,omitempty"`
+	UserAgent  string  `json:"user_agent,omitempty"`
+	Referer    string  `json:"referer,omitempty"`
+	RequestID  string  `json:"request_id,omitempty"`
+	Route      string  `json:"route,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// JSONLogFormat renders entries as JSON objects, one per line.
+func JSONLogFormat(buf []byte, entry *AccessLogEntry) []byte {
+	x := jsonAccessLogEntry{
+		Time:       entry.Time.UTC().Format(time.RFC3339Nano),
+		Method:     entry.Method,
+		URL:        entry.URL,
+		Proto:      entry.Proto,
+		Host:       entry.Host,
+		Status:     entry.Status,
+		RecvBytes:  entry.RecvBytes,
+		SendBytes:  entry.SendBytes,
+		Latency:    entry.Latency.Seconds(),
+		RemoteAddr: entry.RemoteAddr,
+		User:       entry.User,
+		UserAgent:  entry.UserAgent,
+		Referer:    entry.Referer,
+		RequestID:  entry.RequestID,
+		Route:      entry.Route,
+	}
+	if entry.Err != nil {
+		x.Error = entry.Err.Error()
+	}
+
+	raw, err := json.Marshal(x)
+	if err != nil {
+		panic(err)
+	}
+	buf = append(buf, raw...)
+	return append(buf, '\n')
+}
+
+// DefaultAccessLogQueueSize is the queue size used by NewAsyncAccessLog when
+// the given size is not positive.
+const DefaultAccessLogQueueSize = 1024
+
+// AsyncAccessLog is an AccessLogger which formats entries and writes them to
+// an io.Writer from a background goroutine.  LogAccess never blocks: when the
+// queue is full, the entry is dropped and counted instead.
+type AsyncAccessLog struct {
+	format  AccessLogFormat
+	queue   chan []byte
+	done    chan struct{}
+	dropped uint64
+
+	mu     sync.RWMutex
+	closed bool
+	err    error
+}
+
+// NewAsyncAccessLog starts a background goroutine which writes formatted
+// entries to w, queueing at most queueSize entries.  Call Close to flush the
+// queue and stop the goroutine.
+func NewAsyncAccessLog(w io.Writer, format AccessLogFormat, queueSize int) *AsyncAccessLog {
+	if format == nil {
+		format = CombinedLogFormat
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultAccessLogQueueSize
+	}
+
+	log := &AsyncAccessLog{
+		format: format,
+		queue:  make(chan []byte, queueSize),
+		done:   make(chan struct{}),
+	}
+	go log.run(w)
+	return log
+}
+
+func (log *AsyncAccessLog) run(w io.Writer) {
+	defer close(log.done)
+
+	bw := bufio.NewWriter(w)
+	var firstErr error
+	for line := range log.queue {
+		if _, err := bw.Write(line); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if len(log.queue) == 0 {
+			if err := bw.Flush(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if err := bw.Flush(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+
+	log.mu.Lock()
+	log.err = firstErr
+	log.mu.Unlock()
+}
+
+// LogAccess formats the entry and queues it for writing.
+func (log *AsyncAccessLog) LogAccess(entry *AccessLogEntry) {
+	line := log.format(nil, entry)
+
+	log.mu.RLock()
+	defer log.mu.RUnlock()
+
+	if log.closed {
+		atomic.AddUint64(&log.dropped, 1)
+		return
+	}
+
+	select {
+	case log.queue <- line:
+	default:
+		atomic.AddUint64(&log.dropped, 1)
+	}
+}
+
+// Dropped returns the number of entries which were discarded because the
+// queue was full or the log was closed.
+func (log *AsyncAccessLog) Dropped() uint64 {
+	return atomic.LoadUint64(&log.dropped)
+}
+
+// Close writes any queued entries, stops the background goroutine, and
+// returns the first error encountered while writing, if any.
+func (log *AsyncAccessLog) Close() error {
+	log.mu.Lock()
+	if !log.closed {
+		log.closed = true
+		close(log.queue)
+	}
+	log.mu.Unlock()
+
+	<-log.done
+
+	log.mu.RLock()
+	defer log.mu.RUnlock()
+	return log.err
+}
+
+var _ AccessLogger = (*AsyncAccessLog)(nil)
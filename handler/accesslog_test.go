@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chronos-tachyon/morehttp/body"
+	"github.com/chronos-tachyon/morehttp/response"
+)
+
+func entryForTest() *AccessLogEntry {
+	return &AccessLogEntry{
+		Time:       time.Date(2000, time.October, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+		Method:     "GET",
+		URL:        "/apache_pb.gif",
+		Proto:      "HTTP/1.0",
+		Status:     200,
+		SendBytes:  2326,
+		RecvBytes:  10,
+		Latency:    1500 * time.Millisecond,
+		RemoteAddr: "127.0.0.1:4321",
+		User:       "frank",
+		UserAgent:  `Mozilla/4.08 "quoted"`,
+		Referer:    "http://www.example.com/start.html",
+		RequestID:  "abc",
+		Err:        errors.New("oops"),
+	}
+}
+
+func TestCommonLogFormat(t *testing.T) {
+	expect := `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326` + "\n"
+	if actual := string(CommonLogFormat(nil, entryForTest())); expect != actual {
+		t.Errorf("expected %q, got %q", expect, actual)
+	}
+}
+
+func TestCombinedLogFormat(t *testing.T) {
+	expect := `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08 \"quoted\""` + "\n"
+	if actual := string(CombinedLogFormat(nil, entryForTest())); expect != actual {
+		t.Errorf("expected %q, got %q", expect, actual)
+	}
+}
+
+func TestJSONLogFormat(t *testing.T) {
+	line := JSONLogFormat(nil, entryForTest())
+	if !bytes.HasSuffix(line, []byte("\n")) {
+		t.Errorf("expected trailing newline")
+	}
+
+	var x map[string]interface{}
+	if err := json.Unmarshal(line, &x); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+
+	expect := map[string]interface{}{
+		"time":            "2000-10-10T20:55:36Z",
+		"method":          "GET",
+		"status":          200.0,
+		"send_bytes":      2326.0,
+		"recv_bytes":      10.0,
+		"latency_seconds": 1.5,
+		"request_id":      "abc",
+		"error":           "oops",
+	}
+	for key, value := range expect {
+		if x[key] != value {
+			t.Errorf("%s: expected %v, got %v", key, value, x[key])
+		}
+	}
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestAdaptor_AccessLog(t *testing.T) {
+	var out syncBuffer
+	log := NewAsyncAccessLog(&out, CommonLogFormat, 0)
+
+	a := Adaptor{
+		AccessLog: log,
+		Inner: HandlerFunc(func(*http.Request) response.Response {
+			return *response.NewBuilder().WithStatus(http.StatusCreated).WithBody(body.FromString("hello")).Build()
+		}),
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/things?x=1", nil)
+	req.RemoteAddr = "192.0.2.7:5555"
+	a.ServeHTTP(httptest.NewRecorder(), req)
+
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	line := out.String()
+	if !strings.HasPrefix(line, "192.0.2.7 - - [") || !strings.HasSuffix(line, `] "POST /things?x=1 HTTP/1.1" 201 5`+"\n") {
+		t.Errorf("unexpected log line %q", line)
+	}
+	if expect, actual := uint64(0), log.Dropped(); expect != actual {
+		t.Errorf("expected %d dropped, got %d", expect, actual)
+	}
+}
+
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+func TestAsyncAccessLog_Dropped(t *testing.T) {
+	w := &blockingWriter{release: make(chan struct{})}
+	log := NewAsyncAccessLog(w, JSONLogFormat, 2)
+
+	const n = 10
+	for i := 0; i < n; i++ {
+		log.LogAccess(entryForTest())
+	}
+
+	// At most one entry is held by the writer goroutine and two are queued.
+	if dropped := log.Dropped(); dropped < n-3 {
+		t.Errorf("expected at least %d dropped, got %d", n-3, dropped)
+	}
+
+	close(w.release)
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	before := log.Dropped()
+	log.LogAccess(entryForTest())
+	if expect, actual := before+1, log.Dropped(); expect != actual {
+		t.Errorf("expected %d dropped after Close, got %d", expect, actual)
+	}
+}
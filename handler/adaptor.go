@@ -8,7 +8,9 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 
+	"github.com/chronos-tachyon/morehttp/body"
 	"github.com/chronos-tachyon/morehttp/response"
+	"github.com/chronos-tachyon/morehttp/tracing"
 )
 
 var (
@@ -18,6 +20,12 @@ var (
 			Help: "Total number of HTTP requests that triggered a panic().",
 		},
 	)
+	PromTimeoutsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "http_timeouts_total",
+			Help: "Total number of HTTP requests whose handler did not return before the deadline.",
+		},
+	)
 	PromRequestsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "http_requests_total",
@@ -50,68 +58,260 @@ var (
 		prometheus.HistogramOpts{
 			Name:    "http_latency_seconds_hist",
 			Help:    "Histogram of HTTP request latency.",
-			Buckets: []float64{0.005, 0.010, 0.020, 0.050, 0.100, 0.200, 0.500, 1.000, 2.000, 5.000, 10.000},
+			Buckets: DefaultLatencyBuckets,
 		},
 	)
 	PromRecvBytesHist = promauto.NewHistogram(
 		prometheus.HistogramOpts{
 			Name:    "http_recv_bytes_hist",
 			Help:    "Histogram of HTTP request size.",
-			Buckets: prometheus.ExponentialBuckets(1024, 4.0, 6),
+			Buckets: DefaultSizeBuckets,
 		},
 	)
 	PromSendBytesHist = promauto.NewHistogram(
 		prometheus.HistogramOpts{
 			Name:    "http_send_bytes_hist",
 			Help:    "Histogram of HTTP response size.",
-			Buckets: prometheus.ExponentialBuckets(1024, 4.0, 6),
+			Buckets: DefaultSizeBuckets,
+		},
+	)
+	PromCompressInBytesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_compress_uncompressed_bytes_total",
+			Help: "Total number of bytes in compressed HTTP responses, measured before compression, by content coding.",
+		},
+		[]string{"encoding"},
+	)
+	PromCompressOutBytesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_compress_compressed_bytes_total",
+			Help: "Total number of bytes in compressed HTTP responses, measured after compression, by content coding.",
+		},
+		[]string{"encoding"},
+	)
+	PromBufferedBytes = promauto.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "http_body_buffered_bytes",
+			Help: "Number of bytes currently buffered in memory by Bodies charged to body.DefaultBudget.",
+		},
+		func() float64 { return float64(body.DefaultBudget.Used()) },
+	)
+	PromBufferedBodies = promauto.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "http_body_buffered_bodies",
+			Help: "Number of live buffered Bodies charged to body.DefaultBudget.",
 		},
+		func() float64 { return float64(body.DefaultBudget.Bodies()) },
 	)
 )
 
 type Adaptor struct {
 	Inner Handler
+
+	// Compression, if non-nil, enables transparent compression of
+	// eligible responses.
+	Compression *Compression
+
+	// Name identifies this Adaptor in the "handler" metric label.
+	Name string
+
+	// Metrics receives this Adaptor's metrics.  If nil, DefaultMetrics is
+	// used.
+	Metrics *Metrics
+
+	// Timeout, if positive, limits how long Inner.Handle may take.  The
+	// Handler runs under a request context with this deadline; if the
+	// deadline passes before Handle returns, a generated error page is
+	// served instead and the late Response is closed once it arrives.
+	//
+	// The deadline applies only to Handle.  Once Handle returns in time,
+	// the deadline is lifted from the request context, so that a
+	// streaming Response is not cut off while it is being served.
+	//
+	Timeout time.Duration
+
+	// TimeoutCode is the HTTP status code of the page served when Timeout
+	// expires.  If zero, 503 Service Unavailable is used.
+	TimeoutCode int
+
+	// PageGenerator generates the pages which Adaptor serves on its own
+	// behalf.  If nil, response.DefaultPageGenerator is used.
+	PageGenerator response.PageGenerator
+
+	// OnPanic, if non-nil, is called with a PanicError whenever Inner
+	// panics.  If nil, the package-level OnPanic is called instead.
+	//
+	// Panics with the value http.ErrAbortHandler are not reported; they
+	// are re-panicked after metrics are recorded, so that net/http can
+	// abort the response.
+	//
+	OnPanic func(error)
+
+	// RequestID, if non-nil, enables request ID handling.
+	RequestID *RequestIDOptions
+
+	// Tracer, if non-nil, receives a server span for every request.  The
+	// parent span is extracted from the W3C traceparent and tracestate
+	// headers, and the new span is available to Inner via
+	// tracing.SpanFromContext.
+	Tracer tracing.Tracer
+
+	// AccessLog, if non-nil, receives an entry for every request.
+	AccessLog AccessLogger
+
+	// CountHeaderBytes, if true, causes the received byte metrics to
+	// include an estimate of the size of the request line and headers, in
+	// addition to the bytes actually read from the request body.
+	CountHeaderBytes bool
 }
 
 func (a Adaptor) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	ww := response.NewWriter(w, req)
 	startTime := time.Now()
 
+	m := a.Metrics
+	if m == nil {
+		m = DefaultMetrics
+	}
+
+	req, state := withRequestState(req)
+	if a.RequestID != nil {
+		state.requestID = a.RequestID.resolve(req)
+		ww.Header().Set(a.RequestID.header(), state.requestID)
+	}
+
+	var span tracing.Span
+	if a.Tracer != nil {
+		parent, _ := tracing.Extract(req.Header)
+		span = a.Tracer.Start(req.Context(), "HTTP "+req.Method, parent)
+		req = req.WithContext(tracing.ContextWithSpan(req.Context(), span))
+	}
+
+	var headerBytes int64
+	if a.CountHeaderBytes {
+		headerBytes = estimateHeaderBytes(req)
+	}
+	counter := wrapRequestBody(req)
+
+	var respErr error
+
+	var cw *compressWriter
+	if a.Compression != nil {
+		cw = a.Compression.newWriter(ww, req)
+		ww = cw
+	}
+
 	defer func() {
 		panicValue := recover()
+		isAbort := (panicValue == http.ErrAbortHandler)
+
+		var panicErr PanicError
+		if panicValue != nil && !isAbort {
+			panicErr = newPanicError(panicValue)
+			if panicErr.RequestID == "" {
+				panicErr.RequestID = GetRequestID(req)
+			}
+			if ww.Status() == 0 {
+				a.serveErrorPage(ww, req, http.StatusInternalServerError, panicErr)
+			}
+		}
+
+		if cw != nil {
+			_ = cw.finish()
+		}
+		ww.MaybeWriteHeader(http.StatusInternalServerError)
 		code := strconv.Itoa(ww.Status())
 		elapsedDuration := time.Since(startTime)
 		elapsed := float64(elapsedDuration) / float64(time.Second)
-		recvBytes := float64(0.0) // FIXME
+		var recvBodyBytes int64
+		if counter != nil {
+			recvBodyBytes = counter.BytesRead()
+		}
+		recvBytes := float64(headerBytes + recvBodyBytes)
 		sendBytes := float64(ww.BytesWritten())
-		labels := prometheus.Labels{"code": code}
+		labels := m.labels(req, a.Name)
 
-		ww.MaybeWriteHeader(http.StatusInternalServerError)
-		if panicValue != nil {
-			PromPanicsTotal.Inc()
+		m.observeRequest(requestObservation{
+			labels:    labels,
+			code:      code,
+			panicked:  panicValue != nil && !isAbort,
+			elapsed:   elapsed,
+			recvBytes: recvBytes,
+			sendBytes: sendBytes,
+		})
+		if cw != nil && cw.encoding != "" {
+			m.observeCompression(labels, cw.encoding, cw.uncompressed, cw.Writer.BytesWritten())
 		}
-		PromRequestsTotal.With(labels).Inc()
-		PromLatencyTotal.With(labels).Add(elapsed)
-		PromRecvBytesTotal.With(labels).Add(recvBytes)
-		PromSendBytesTotal.With(labels).Add(sendBytes)
-		PromLatencyHist.Observe(elapsed)
-		PromRecvBytesHist.Observe(recvBytes)
-		PromSendBytesHist.Observe(sendBytes)
 
-		if panicValue != nil {
-			err, ok := panicValue.(error)
-			if !ok {
-				err = PanicError{Value: panicValue}
+		if span != nil {
+			spanErr := respErr
+			if isAbort {
+				spanErr = http.ErrAbortHandler
+			} else if panicValue != nil {
+				spanErr = panicErr
 			}
-			OnPanic(err)
+			finishSpan(span, req, ww.Status(), recvBodyBytes, ww.BytesWritten(), spanErr, panicValue != nil)
+		}
+
+		if a.AccessLog != nil {
+			entry := newAccessLogEntry(req, startTime)
+			entry.Status = ww.Status()
+			entry.RecvBytes = int64(recvBytes)
+			entry.SendBytes = ww.BytesWritten()
+			entry.Latency = elapsedDuration
+			entry.RequestID = GetRequestID(req)
+			entry.Route = RouteName(req)
+			entry.Err = respErr
+			if panicValue != nil && !isAbort {
+				entry.Err = panicErr
+			}
+			a.AccessLog.LogAccess(entry)
+		}
+
+		if isAbort {
+			panic(panicValue)
+		}
+		if panicValue != nil {
+			a.onPanic(panicErr)
 		}
 	}()
 
-	resp := a.Inner.Handle(req)
-	err := resp.Serve(ww)
+	var resp response.Response
+	if a.Timeout > 0 {
+		ctx := newHandleContext(req.Context(), a.Timeout)
+		defer ctx.release()
+
+		req = req.WithContext(ctx)
+
+		var timedOut bool
+		resp, timedOut = a.handleWithTimeout(ctx, req, m)
+		if timedOut {
+			m.observeTimeout(m.labels(req, a.Name))
+		} else {
+			ctx.lift()
+		}
+	} else {
+		resp = a.Inner.Handle(req)
+	}
+	respErr = resp.Err()
+	err := resp.ServeRequest(ww, req)
 	if err != nil {
 		panic(err)
 	}
 }
 
+func (a Adaptor) newBuilder(req *http.Request) *response.Builder {
+	builder := response.NewBuilder()
+	if a.PageGenerator != nil {
+		builder.WithPageGenerator(a.PageGenerator)
+	}
+	builder.WithRequestID(GetRequestID(req))
+	return builder
+}
+
+func (a Adaptor) serveErrorPage(w http.ResponseWriter, req *http.Request, code int, err error) {
+	resp := a.newBuilder(req).ErrorPage(code, err).Build()
+	_ = resp.Serve(w)
+}
+
 var _ http.Handler = Adaptor{}
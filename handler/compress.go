@@ -0,0 +1,341 @@
+package handler
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/chronos-tachyon/morehttp/internal/negotiate"
+	"github.com/chronos-tachyon/morehttp/response"
+)
+
+// DefaultMinCompressLength is the default value of Compression.MinLength.
+const DefaultMinCompressLength = 1024
+
+// Encoder is a function which wraps an io.Writer with a compressing
+// io.WriteCloser.  Closing the returned io.WriteCloser must flush any
+// buffered data to w, but must not close w itself.
+//
+// If the returned io.WriteCloser also has a "Flush() error" method, then it
+// will be called whenever the response is flushed.
+//
+type Encoder func(w io.Writer) (io.WriteCloser, error)
+
+// EncoderRegistry is a collection of Encoders, indexed by the name of the
+// content coding which they implement.
+type EncoderRegistry struct {
+	mu       sync.RWMutex
+	encoders map[string]Encoder
+	names    []string
+}
+
+// NewEncoderRegistry constructs an empty EncoderRegistry.
+func NewEncoderRegistry() *EncoderRegistry {
+	return &EncoderRegistry{encoders: make(map[string]Encoder, 4)}
+}
+
+// Register adds an Encoder for the named content coding, replacing any
+// existing Encoder for that coding.
+//
+// When a client finds several content codings equally acceptable, the coding
+// registered most recently is preferred.
+//
+func (reg *EncoderRegistry) Register(name string, enc Encoder) {
+	if enc == nil {
+		panic("Encoder is nil")
+	}
+
+	name = strings.ToLower(name)
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	reg.removeLocked(name)
+	reg.encoders[name] = enc
+	reg.names = append([]string{name}, reg.names...)
+}
+
+// Unregister removes the Encoder for the named content coding, if any.
+func (reg *EncoderRegistry) Unregister(name string) {
+	name = strings.ToLower(name)
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	reg.removeLocked(name)
+}
+
+func (reg *EncoderRegistry) removeLocked(name string) {
+	if _, found := reg.encoders[name]; !found {
+		return
+	}
+	delete(reg.encoders, name)
+	for index, other := range reg.names {
+		if other == name {
+			reg.names = append(reg.names[:index:index], reg.names[index+1:]...)
+			break
+		}
+	}
+}
+
+// Lookup returns the Encoder for the named content coding.
+func (reg *EncoderRegistry) Lookup(name string) (Encoder, bool) {
+	name = strings.ToLower(name)
+
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	enc, found := reg.encoders[name]
+	return enc, found
+}
+
+// Names returns the names of all registered content codings, most preferred
+// first.
+func (reg *EncoderRegistry) Names() []string {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	out := make([]string, len(reg.names))
+	copy(out, reg.names)
+	return out
+}
+
+// GzipEncoder is an Encoder for the "gzip" content coding.
+func GzipEncoder(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+// DeflateEncoder is an Encoder for the "deflate" content coding.
+//
+// As required by RFC 7230 section 4.2.2, this produces a zlib stream rather
+// than raw DEFLATE data.
+//
+func DeflateEncoder(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriterLevel(w, flate.DefaultCompression)
+}
+
+// DefaultEncoders is the EncoderRegistry used when Compression.Encoders is
+// nil.  It initially contains "gzip" and "deflate", preferring "gzip".
+// Callers may register additional codings, such as "br" or "zstd".
+var DefaultEncoders = newDefaultEncoders()
+
+func newDefaultEncoders() *EncoderRegistry {
+	reg := NewEncoderRegistry()
+	reg.Register("deflate", DeflateEncoder)
+	reg.Register("gzip", GzipEncoder)
+	return reg
+}
+
+// DefaultIsCompressible is the default value of Compression.IsCompressible.
+// It accepts textual media types, plus JSON, XML, JavaScript, and a few
+// others which are known to compress well.
+func DefaultIsCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch mediaType {
+	case "text/event-stream":
+		return false
+	case "application/json":
+		return true
+	case "application/javascript":
+		return true
+	case "application/x-ndjson":
+		return true
+	case "application/xml":
+		return true
+	case "application/wasm":
+		return true
+	case "image/svg+xml":
+		return true
+	case "image/bmp":
+		return true
+	case "font/ttf":
+		return true
+	case "font/otf":
+		return true
+	}
+
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	if strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	return false
+}
+
+// Compression configures transparent compression of responses by Adaptor.
+//
+// A response is compressed only if it has status 200, does not already have
+// a Content-Encoding or Content-Range, has a compressible Content-Type, is
+// not known to be shorter than MinLength, and the client accepts one of the
+// registered content codings.
+//
+type Compression struct {
+	// Encoders lists the available content codings.  If nil,
+	// DefaultEncoders is used.
+	Encoders *EncoderRegistry
+
+	// MinLength is the smallest Content-Length which is worth compressing.
+	// Responses of unknown length are always eligible.  If zero,
+	// DefaultMinCompressLength is used; if negative, there is no minimum.
+	MinLength int64
+
+	// IsCompressible reports whether the given Content-Type is worth
+	// compressing.  If nil, DefaultIsCompressible is used.
+	IsCompressible func(contentType string) bool
+}
+
+func (c *Compression) encoders() *EncoderRegistry {
+	if c.Encoders == nil {
+		return DefaultEncoders
+	}
+	return c.Encoders
+}
+
+func (c *Compression) minLength() int64 {
+	if c.MinLength == 0 {
+		return DefaultMinCompressLength
+	}
+	return c.MinLength
+}
+
+func (c *Compression) isCompressible(contentType string) bool {
+	if c.IsCompressible == nil {
+		return DefaultIsCompressible(contentType)
+	}
+	return c.IsCompressible(contentType)
+}
+
+func (c *Compression) newWriter(w response.Writer, req *http.Request) *compressWriter {
+	return &compressWriter{
+		Writer:         w,
+		c:              c,
+		acceptEncoding: req.Header.Values("Accept-Encoding"),
+	}
+}
+
+type compressWriter struct {
+	response.Writer
+	c              *Compression
+	acceptEncoding []string
+	enc            io.WriteCloser
+	encoding       string
+	uncompressed   int64
+	closed         bool
+}
+
+func (w *compressWriter) decide(status int) {
+	if status != http.StatusOK {
+		return
+	}
+
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return
+	}
+	if !w.c.isCompressible(h.Get("Content-Type")) {
+		return
+	}
+
+	negotiate.AddVary(h, "Accept-Encoding")
+
+	if str := h.Get("Content-Length"); str != "" {
+		length, err := strconv.ParseInt(str, 10, 64)
+		if err == nil && length < w.c.minLength() {
+			return
+		}
+	}
+
+	reg := w.c.encoders()
+	available := append(reg.Names(), "identity")
+	name, ok := negotiate.SelectEncoding(w.acceptEncoding, available)
+	if !ok || name == "identity" {
+		return
+	}
+
+	fn, ok := reg.Lookup(name)
+	if !ok {
+		return
+	}
+
+	enc, err := fn(w.Writer)
+	if err != nil {
+		return
+	}
+
+	h.Set("Content-Encoding", name)
+	h.Del("Content-Length")
+	h.Del("Accept-Ranges")
+	if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+		h.Set("ETag", "W/"+etag)
+	}
+
+	w.enc = enc
+	w.encoding = name
+}
+
+func (w *compressWriter) MaybeWriteHeader(status int) {
+	if w.Status() != 0 {
+		return
+	}
+	w.WriteHeader(status)
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.Status() == 0 {
+		w.decide(status)
+	}
+	w.Writer.WriteHeader(status)
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	w.MaybeWriteHeader(http.StatusOK)
+
+	if w.enc == nil {
+		return w.Writer.Write(p)
+	}
+
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	n, err := w.enc.Write(p)
+	w.uncompressed += int64(n)
+	return n, err
+}
+
+func (w *compressWriter) Flush() {
+	w.MaybeWriteHeader(http.StatusOK)
+
+	if x, ok := w.enc.(interface{ Flush() error }); ok && !w.closed {
+		_ = x.Flush()
+	}
+	if x, ok := w.Writer.(http.Flusher); ok {
+		x.Flush()
+	}
+}
+
+// finish closes the Encoder, if any.
+func (w *compressWriter) finish() error {
+	if w.enc == nil || w.closed {
+		return nil
+	}
+
+	w.closed = true
+	return w.enc.Close()
+}
+
+var (
+	_ response.Writer = (*compressWriter)(nil)
+	_ http.Flusher    = (*compressWriter)(nil)
+)
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chronos-tachyon/morehttp/body"
+	"github.com/chronos-tachyon/morehttp/response"
+)
+
+func serveCompressedForTest(t *testing.T, c *Compression, builder *response.Builder, acceptEncoding string) *http.Response {
+	t.Helper()
+
+	a := Adaptor{
+		Inner: HandlerFunc(func(*http.Request) response.Response {
+			return *builder.Build()
+		}),
+		Compression: c,
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, req)
+	return w.Result()
+}
+
+func TestAdaptor_Compression(t *testing.T) {
+	content := strings.Repeat("hello, world! ", 200)
+
+	newBuilder := func() *response.Builder {
+		return response.NewBuilder().
+			WithContentType("text/plain; charset=utf-8").
+			WithETag("v1", true).
+			WithBody(body.FromString(content))
+	}
+
+	t.Run("Gzip", func(t *testing.T) {
+		result := serveCompressedForTest(t, &Compression{}, newBuilder(), "deflate, gzip")
+		if expect, actual := "gzip", result.Header.Get("Content-Encoding"); expect != actual {
+			t.Fatalf("expected Content-Encoding %q, got %q", expect, actual)
+		}
+		if expect, actual := "Accept-Encoding", result.Header.Get("Vary"); expect != actual {
+			t.Errorf("expected Vary %q, got %q", expect, actual)
+		}
+		if actual := result.Header.Get("Content-Length"); actual != "" {
+			t.Errorf("expected no Content-Length, got %q", actual)
+		}
+		if expect, actual := `W/"v1"`, result.Header.Get("ETag"); expect != actual {
+			t.Errorf("expected ETag %q, got %q", expect, actual)
+		}
+
+		zr, err := gzip.NewReader(result.Body)
+		if err != nil {
+			t.Fatalf("gzip.NewReader failed: %v", err)
+		}
+		raw, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("ReadAll failed: %v", err)
+		}
+		if string(raw) != content {
+			t.Errorf("decompressed body does not match")
+		}
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		reg := NewEncoderRegistry()
+		reg.Register("gzip", GzipEncoder)
+		reg.Register("x-test", func(w io.Writer) (io.WriteCloser, error) {
+			return nopWriteCloser{w}, nil
+		})
+
+		result := serveCompressedForTest(t, &Compression{Encoders: reg}, newBuilder(), "gzip, x-test")
+		if expect, actual := "x-test", result.Header.Get("Content-Encoding"); expect != actual {
+			t.Errorf("expected Content-Encoding %q, got %q", expect, actual)
+		}
+	})
+
+	type testRow struct {
+		Name           string
+		Compression    *Compression
+		Builder        *response.Builder
+		AcceptEncoding string
+		Vary           string
+	}
+
+	testData := []testRow{
+		{"Disabled", nil, newBuilder(), "gzip", ""},
+		{"NotAccepted", &Compression{}, newBuilder(), "", "Accept-Encoding"},
+		{"Unknown", &Compression{}, newBuilder(), "compress", "Accept-Encoding"},
+		{"Tiny", &Compression{}, newBuilder().WithBody(body.FromString("abc")), "gzip", "Accept-Encoding"},
+		{"AlreadyEncoded", &Compression{}, newBuilder().WithContentEncoding("br"), "gzip", ""},
+		{"NotCompressible", &Compression{}, newBuilder().WithContentType("image/png"), "gzip", ""},
+	}
+
+	for _, row := range testData {
+		t.Run(row.Name, func(t *testing.T) {
+			result := serveCompressedForTest(t, row.Compression, row.Builder, row.AcceptEncoding)
+			if actual := result.Header.Get("Content-Encoding"); actual == "gzip" {
+				t.Errorf("expected no gzip Content-Encoding")
+			}
+			if expect, actual := row.Vary, result.Header.Get("Vary"); expect != actual {
+				t.Errorf("expected Vary %q, got %q", expect, actual)
+			}
+		})
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
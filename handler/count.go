@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// countingBody wraps an http.Request's Body, counting the bytes read from it.
+type countingBody struct {
+	inner io.ReadCloser
+	count int64
+}
+
+func (body *countingBody) Read(p []byte) (int, error) {
+	n, err := body.inner.Read(p)
+	atomic.AddInt64(&body.count, int64(n))
+	return n, err
+}
+
+func (body *countingBody) Close() error {
+	return body.inner.Close()
+}
+
+func (body *countingBody) BytesRead() int64 {
+	return atomic.LoadInt64(&body.count)
+}
+
+// countingWriterToBody is a countingBody whose inner Body provides
+// io.WriterTo.
+type countingWriterToBody struct {
+	countingBody
+}
+
+func (body *countingWriterToBody) WriteTo(w io.Writer) (int64, error) {
+	n, err := body.inner.(io.WriterTo).WriteTo(w)
+	atomic.AddInt64(&body.count, n)
+	return n, err
+}
+
+type byteCounter interface {
+	io.ReadCloser
+	BytesRead() int64
+}
+
+// wrapRequestBody replaces req.Body with a counting wrapper.  Returns nil if
+// the request has no body.
+func wrapRequestBody(req *http.Request) byteCounter {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	var counter byteCounter
+	if _, ok := req.Body.(io.WriterTo); ok {
+		counter = &countingWriterToBody{countingBody{inner: req.Body}}
+	} else {
+		counter = &countingBody{inner: req.Body}
+	}
+	req.Body = counter
+	return counter
+}
+
+// estimateHeaderBytes estimates the number of bytes in the request line and
+// headers of the given request, as they would appear in HTTP/1.1 wire format.
+func estimateHeaderBytes(req *http.Request) int64 {
+	uri := req.RequestURI
+	if uri == "" && req.URL != nil {
+		uri = req.URL.RequestURI()
+	}
+
+	// "METHOD URI PROTO\r\n"
+	n := int64(len(req.Method) + 1 + len(uri) + 1 + len(req.Proto) + 2)
+
+	if req.Host != "" {
+		// "Host: example.com\r\n"
+		n += int64(4 + 2 + len(req.Host) + 2)
+	}
+
+	for name, values := range req.Header {
+		for _, value := range values {
+			// "Name: value\r\n"
+			n += int64(len(name) + 2 + len(value) + 2)
+		}
+	}
+
+	// Final "\r\n"
+	n += 2
+	return n
+}
+
+var (
+	_ io.ReadCloser = (*countingBody)(nil)
+	_ io.ReadCloser = (*countingWriterToBody)(nil)
+	_ io.WriterTo   = (*countingWriterToBody)(nil)
+)
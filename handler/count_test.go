@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/chronos-tachyon/morehttp/body"
+	"github.com/chronos-tachyon/morehttp/response"
+)
+
+func TestCountingBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("abcdefgh"))
+	counter := wrapRequestBody(req)
+	if counter == nil {
+		t.Fatal("wrapRequestBody returned nil")
+	}
+
+	buf := make([]byte, 3)
+	if _, err := io.ReadFull(req.Body, buf); err != nil {
+		t.Fatalf("ReadFull failed: %v", err)
+	}
+	if expect, actual := int64(3), counter.BytesRead(); expect != actual {
+		t.Errorf("expected %d bytes read, got %d", expect, actual)
+	}
+
+	if _, ok := req.Body.(io.WriterTo); !ok {
+		t.Fatalf("expected wrapped Body to provide io.WriterTo")
+	}
+	var sb strings.Builder
+	if _, err := io.Copy(&sb, req.Body); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if expect, actual := "defgh", sb.String(); expect != actual {
+		t.Errorf("expected %q, got %q", expect, actual)
+	}
+	if expect, actual := int64(8), counter.BytesRead(); expect != actual {
+		t.Errorf("expected %d bytes read, got %d", expect, actual)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	if counter := wrapRequestBody(req); counter != nil {
+		t.Errorf("expected nil counter for request without body")
+	}
+}
+
+func TestAdaptor_RecvBytes(t *testing.T) {
+	type testRow struct {
+		Name        string
+		Handler     HandlerFunc
+		CountHeader bool
+		Code        string
+		Expect      float64
+	}
+
+	testData := []testRow{
+		{
+			Name: "Partial",
+			Handler: func(req *http.Request) response.Response {
+				buf := make([]byte, 4)
+				_, _ = io.ReadFull(req.Body, buf)
+				return *response.NewBuilder().WithStatus(http.StatusAccepted).WithBody(body.Empty()).Build()
+			},
+			Code:   "202",
+			Expect: 4,
+		},
+		{
+			Name: "Panic",
+			Handler: func(req *http.Request) response.Response {
+				buf := make([]byte, 6)
+				_, _ = io.ReadFull(req.Body, buf)
+				panic("boom")
+			},
+			Code:   "500",
+			Expect: 6,
+		},
+		{
+			Name: "WithHeaders",
+			Handler: func(req *http.Request) response.Response {
+				_, _ = io.Copy(io.Discard, req.Body)
+				return *response.NewBuilder().WithStatus(http.StatusCreated).WithBody(body.Empty()).Build()
+			},
+			CountHeader: true,
+			Code:        "201",
+			// "POST /x HTTP/1.1\r\n" + "Host: example.com\r\n" + "\r\n" + body
+			Expect: 18 + 19 + 2 + 10,
+		},
+	}
+
+	for _, row := range testData {
+		t.Run(row.Name, func(t *testing.T) {
+			labels := prometheus.Labels{"code": row.Code}
+			before := testutil.ToFloat64(PromRecvBytesTotal.With(labels))
+
+			a := Adaptor{Inner: row.Handler, CountHeaderBytes: row.CountHeader}
+			req := httptest.NewRequest(http.MethodPost, "/x", strings.NewReader("0123456789"))
+			w := httptest.NewRecorder()
+			a.ServeHTTP(w, req)
+
+			after := testutil.ToFloat64(PromRecvBytesTotal.With(labels))
+			if actual := after - before; actual != row.Expect {
+				t.Errorf("expected %v bytes received, got %v", row.Expect, actual)
+			}
+		})
+	}
+}
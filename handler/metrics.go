@@ -0,0 +1,321 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/chronos-tachyon/morehttp/body"
+)
+
+// Label names which may be listed in MetricsOptions.Labels.
+const (
+	LabelMethod  = "method"
+	LabelRoute   = "route"
+	LabelHandler = "handler"
+)
+
+var (
+	// DefaultLatencyBuckets is the default value of
+	// MetricsOptions.LatencyBuckets.
+	DefaultLatencyBuckets = []float64{0.005, 0.010, 0.020, 0.050, 0.100, 0.200, 0.500, 1.000, 2.000, 5.000, 10.000}
+
+	// DefaultSizeBuckets is the default value of MetricsOptions.SizeBuckets.
+	DefaultSizeBuckets = prometheus.ExponentialBuckets(1024, 4.0, 6)
+)
+
+// MetricsOptions configures a Metrics instance created by NewMetrics.
+type MetricsOptions struct {
+	// Registerer is the registry which the metrics are registered with.
+	// If nil, prometheus.DefaultRegisterer is used.
+	Registerer prometheus.Registerer
+
+	// Namespace and Subsystem are prepended to each metric name.
+	Namespace string
+	Subsystem string
+
+	// LatencyBuckets are the histogram buckets for request latency, in
+	// seconds.  If nil, DefaultLatencyBuckets is used.
+	LatencyBuckets []float64
+
+	// SizeBuckets are the histogram buckets for request and response
+	// sizes, in bytes.  If nil, DefaultSizeBuckets is used.
+	SizeBuckets []float64
+
+	// Labels lists additional labels to attach to every metric.  Each
+	// must be one of LabelMethod, LabelRoute, or LabelHandler.
+	//
+	// The route label is populated by SetRouteName, and the handler label
+	// is populated from Adaptor.Name.
+	//
+	Labels []string
+
+	// Budget, if non-nil, is reported by gauges for the number of bytes
+	// buffered in memory and the number of live buffered Bodies.
+	Budget *body.Budget
+}
+
+// Metrics is a set of Prometheus metrics which an Adaptor updates as it
+// serves requests.
+type Metrics struct {
+	extra            []string
+	panics           counterFamily
+	timeouts         counterFamily
+	requests         counterFamily
+	latency          counterFamily
+	recvBytes        counterFamily
+	sendBytes        counterFamily
+	latencyHist      histogramFamily
+	recvBytesHist    histogramFamily
+	sendBytesHist    histogramFamily
+	compressInBytes  counterFamily
+	compressOutBytes counterFamily
+}
+
+// DefaultMetrics is the Metrics instance used by Adaptors whose Metrics field
+// is nil.  It updates the package-level Prom* metrics.
+var DefaultMetrics = &Metrics{
+	panics:           singleCounter{PromPanicsTotal},
+	timeouts:         singleCounter{PromTimeoutsTotal},
+	requests:         counterVecFamily{PromRequestsTotal},
+	latency:          counterVecFamily{PromLatencyTotal},
+	recvBytes:        counterVecFamily{PromRecvBytesTotal},
+	sendBytes:        counterVecFamily{PromSendBytesTotal},
+	latencyHist:      singleHistogram{PromLatencyHist},
+	recvBytesHist:    singleHistogram{PromRecvBytesHist},
+	sendBytesHist:    singleHistogram{PromSendBytesHist},
+	compressInBytes:  counterVecFamily{PromCompressInBytesTotal},
+	compressOutBytes: counterVecFamily{PromCompressOutBytesTotal},
+}
+
+// NewMetrics creates and registers a new set of metrics.
+//
+// The metric names match those of the package-level Prom* metrics, so a
+// distinct Namespace, Subsystem, or Registerer is needed to avoid conflicts
+// with DefaultMetrics.
+//
+func NewMetrics(opts MetricsOptions) (*Metrics, error) {
+	reg := opts.Registerer
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	latencyBuckets := opts.LatencyBuckets
+	if latencyBuckets == nil {
+		latencyBuckets = DefaultLatencyBuckets
+	}
+
+	sizeBuckets := opts.SizeBuckets
+	if sizeBuckets == nil {
+		sizeBuckets = DefaultSizeBuckets
+	}
+
+	seen := make(map[string]bool, len(opts.Labels))
+	extra := make([]string, 0, len(opts.Labels))
+	for _, name := range opts.Labels {
+		switch name {
+		case LabelMethod, LabelRoute, LabelHandler:
+		default:
+			return nil, fmt.Errorf("unknown metric label %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate metric label %q", name)
+		}
+		seen[name] = true
+		extra = append(extra, name)
+	}
+
+	withCode := append([]string{"code"}, extra...)
+	withEncoding := append([]string{"encoding"}, extra...)
+
+	var collectors []prometheus.Collector
+
+	counter := func(name, help string, labels []string) counterFamily {
+		vec := prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: opts.Namespace,
+				Subsystem: opts.Subsystem,
+				Name:      name,
+				Help:      help,
+			},
+			labels,
+		)
+		collectors = append(collectors, vec)
+		return counterVecFamily{vec}
+	}
+
+	histogram := func(name, help string, buckets []float64) histogramFamily {
+		vec := prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: opts.Namespace,
+				Subsystem: opts.Subsystem,
+				Name:      name,
+				Help:      help,
+				Buckets:   buckets,
+			},
+			extra,
+		)
+		collectors = append(collectors, vec)
+		return histogramVecFamily{vec}
+	}
+
+	m := &Metrics{
+		extra:            extra,
+		panics:           counter("http_panics_total", "Total number of HTTP requests that triggered a panic().", extra),
+		timeouts:         counter("http_timeouts_total", "Total number of HTTP requests whose handler did not return before the deadline.", extra),
+		requests:         counter("http_requests_total", "Total number of HTTP requests by status code.", withCode),
+		latency:          counter("http_latency_total", "Total number of wall-time seconds spent on handling HTTP responses by status code.", withCode),
+		recvBytes:        counter("http_recv_bytes_total", "Total number of bytes received in HTTP requests by status code.", withCode),
+		sendBytes:        counter("http_send_bytes_total", "Total number of bytes sent in HTTP responses by status code.", withCode),
+		latencyHist:      histogram("http_latency_seconds_hist", "Histogram of HTTP request latency.", latencyBuckets),
+		recvBytesHist:    histogram("http_recv_bytes_hist", "Histogram of HTTP request size.", sizeBuckets),
+		sendBytesHist:    histogram("http_send_bytes_hist", "Histogram of HTTP response size.", sizeBuckets),
+		compressInBytes:  counter("http_compress_uncompressed_bytes_total", "Total number of bytes in compressed HTTP responses, measured before compression, by content coding.", withEncoding),
+		compressOutBytes: counter("http_compress_compressed_bytes_total", "Total number of bytes in compressed HTTP responses, measured after compression, by content coding.", withEncoding),
+	}
+
+	if budget := opts.Budget; budget != nil {
+		gauge := func(name, help string, fn func() float64) {
+			collectors = append(collectors, prometheus.NewGaugeFunc(
+				prometheus.GaugeOpts{
+					Namespace: opts.Namespace,
+					Subsystem: opts.Subsystem,
+					Name:      name,
+					Help:      help,
+				},
+				fn,
+			))
+		}
+		gauge("http_body_buffered_bytes", "Number of bytes currently buffered in memory by Bodies charged to the budget.", func() float64 { return float64(budget.Used()) })
+		gauge("http_body_buffered_bodies", "Number of live buffered Bodies charged to the budget.", func() float64 { return float64(budget.Bodies()) })
+	}
+
+	for index, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			for _, other := range collectors[:index] {
+				reg.Unregister(other)
+			}
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// labels returns the values of the extra labels for the given request.
+func (m *Metrics) labels(req *http.Request, handlerName string) prometheus.Labels {
+	labels := make(prometheus.Labels, len(m.extra)+1)
+	for _, name := range m.extra {
+		switch name {
+		case LabelMethod:
+			labels[name] = normalizeMethod(req.Method)
+		case LabelRoute:
+			labels[name] = RouteName(req)
+		case LabelHandler:
+			labels[name] = handlerName
+		}
+	}
+	return labels
+}
+
+type requestObservation struct {
+	labels    prometheus.Labels
+	code      string
+	panicked  bool
+	elapsed   float64
+	recvBytes float64
+	sendBytes float64
+}
+
+func (m *Metrics) observeRequest(o requestObservation) {
+	withCode := make(prometheus.Labels, len(o.labels)+1)
+	for k, v := range o.labels {
+		withCode[k] = v
+	}
+	withCode["code"] = o.code
+
+	if o.panicked {
+		m.observePanic(o.labels)
+	}
+	m.requests.with(withCode).Inc()
+	m.latency.with(withCode).Add(o.elapsed)
+	m.recvBytes.with(withCode).Add(o.recvBytes)
+	m.sendBytes.with(withCode).Add(o.sendBytes)
+	m.latencyHist.with(o.labels).Observe(o.elapsed)
+	m.recvBytesHist.with(o.labels).Observe(o.recvBytes)
+	m.sendBytesHist.with(o.labels).Observe(o.sendBytes)
+}
+
+func (m *Metrics) observePanic(labels prometheus.Labels) {
+	m.panics.with(labels).Inc()
+}
+
+func (m *Metrics) observeTimeout(labels prometheus.Labels) {
+	m.timeouts.with(labels).Inc()
+}
+
+func (m *Metrics) observeCompression(labels prometheus.Labels, encoding string, in int64, out int64) {
+	withEncoding := make(prometheus.Labels, len(labels)+1)
+	for k, v := range labels {
+		withEncoding[k] = v
+	}
+	withEncoding["encoding"] = encoding
+
+	m.compressInBytes.with(withEncoding).Add(float64(in))
+	m.compressOutBytes.with(withEncoding).Add(float64(out))
+}
+
+func normalizeMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
+
+// counterFamily abstracts over a single prometheus.Counter and a
+// *prometheus.CounterVec, so that DefaultMetrics can keep using the
+// package-level metrics.  A single Counter ignores the labels.
+type counterFamily interface {
+	with(labels prometheus.Labels) prometheus.Counter
+}
+
+type singleCounter struct {
+	c prometheus.Counter
+}
+
+func (f singleCounter) with(labels prometheus.Labels) prometheus.Counter {
+	return f.c
+}
+
+type counterVecFamily struct {
+	vec *prometheus.CounterVec
+}
+
+func (f counterVecFamily) with(labels prometheus.Labels) prometheus.Counter {
+	return f.vec.With(labels)
+}
+
+// histogramFamily is the histogram equivalent of counterFamily.
+type histogramFamily interface {
+	with(labels prometheus.Labels) prometheus.Observer
+}
+
+type singleHistogram struct {
+	h prometheus.Histogram
+}
+
+func (f singleHistogram) with(labels prometheus.Labels) prometheus.Observer {
+	return f.h
+}
+
+type histogramVecFamily struct {
+	vec *prometheus.HistogramVec
+}
+
+func (f histogramVecFamily) with(labels prometheus.Labels) prometheus.Observer {
+	return f.vec.With(labels)
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/chronos-tachyon/morehttp/body"
+	"github.com/chronos-tachyon/morehttp/response"
+)
+
+func TestNewMetrics(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	m, err := NewMetrics(MetricsOptions{
+		Registerer: reg,
+		Namespace:  "test",
+		Labels:     []string{LabelMethod, LabelRoute, LabelHandler},
+	})
+	if err != nil {
+		t.Fatalf("NewMetrics failed: %v", err)
+	}
+
+	a := Adaptor{
+		Name:    "widgets",
+		Metrics: m,
+		Inner: HandlerFunc(func(req *http.Request) response.Response {
+			SetRouteName(req, "/widgets/{id}")
+			return *response.NewBuilder().WithBody(body.FromString("ok")).Build()
+		}),
+	}
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPut, "/widgets/42", nil)
+		a.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	req := httptest.NewRequest("BREW", "/widgets/42", nil)
+	a.ServeHTTP(httptest.NewRecorder(), req)
+
+	type testRow struct {
+		Method string
+		Expect float64
+	}
+
+	for _, row := range []testRow{{"PUT", 2}, {"OTHER", 1}} {
+		labels := prometheus.Labels{
+			"code":       "200",
+			LabelMethod:  row.Method,
+			LabelRoute:   "/widgets/{id}",
+			LabelHandler: "widgets",
+		}
+		if actual := testutil.ToFloat64(m.requests.with(labels)); actual != row.Expect {
+			t.Errorf("%s: expected %v requests, got %v", row.Method, row.Expect, actual)
+		}
+	}
+
+	if n, err := testutil.GatherAndCount(reg, "test_http_latency_seconds_hist"); err != nil || n != 2 {
+		t.Errorf("expected 2 latency histograms, got %d (err=%v)", n, err)
+	}
+
+	if _, err := NewMetrics(MetricsOptions{Registerer: reg, Namespace: "test"}); err == nil {
+		t.Errorf("expected error when registering duplicate metrics")
+	}
+
+	if _, err := NewMetrics(MetricsOptions{Registerer: prometheus.NewRegistry(), Labels: []string{"bogus"}}); err == nil {
+		t.Errorf("expected error for unknown label")
+	}
+}
+
+func TestRouteName(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	SetRouteName(req, "ignored")
+	if actual := RouteName(req); actual != "" {
+		t.Errorf("expected empty route name outside of Adaptor, got %q", actual)
+	}
+
+	req, _ = withRequestState(req)
+	SetRouteName(req, "root")
+	if expect, actual := "root", RouteName(req); expect != actual {
+		t.Errorf("expected route name %q, got %q", expect, actual)
+	}
+}
+
+func TestNewMetrics_Budget(t *testing.T) {
+	budget := body.NewBudget(0)
+
+	reg := prometheus.NewPedanticRegistry()
+	_, err := NewMetrics(MetricsOptions{
+		Registerer: reg,
+		Namespace:  "test",
+		Budget:     budget,
+	})
+	if err != nil {
+		t.Fatalf("NewMetrics failed: %v", err)
+	}
+
+	b, err := body.FromReaderWithOptions(io.MultiReader(strings.NewReader("abcd")), -1, &body.BufferOptions{Budget: budget})
+	if err != nil {
+		t.Fatalf("FromReaderWithOptions failed: %v", err)
+	}
+	defer b.Close()
+
+	if _, err := b.Read(make([]byte, 2)); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	expect := `
+# HELP test_http_body_buffered_bodies Number of live buffered Bodies charged to the budget.
+# TYPE test_http_body_buffered_bodies gauge
+test_http_body_buffered_bodies 1
+# HELP test_http_body_buffered_bytes Number of bytes currently buffered in memory by Bodies charged to the budget.
+# TYPE test_http_body_buffered_bytes gauge
+test_http_body_buffered_bytes 65536
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expect), "test_http_body_buffered_bodies", "test_http_body_buffered_bytes"); err != nil {
+		t.Errorf("GatherAndCompare: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/chronos-tachyon/morehttp/response"
+)
+
+// Middleware wraps a Handler to produce another Handler.  Because Handlers
+// return a Response instead of writing one, a Middleware can inspect and
+// rewrite the Response (see response.Response.ToBuilder) as well as the
+// request.
+type Middleware func(Handler) Handler
+
+// Chain wraps h with the given Middleware.  The first Middleware listed is the
+// outermost, i.e. it sees the request first and the Response last.
+func Chain(h Handler, list ...Middleware) Handler {
+	for i := len(list) - 1; i >= 0; i-- {
+		h = list[i](h)
+	}
+	return h
+}
+
+// InjectHeaders returns a Middleware which adds the given headers to every
+// Response.  If overwrite is false, headers which the Response already has
+// are left alone.
+func InjectHeaders(hdrs http.Header, overwrite bool) Middleware {
+	hdrs = hdrs.Clone()
+	return func(inner Handler) Handler {
+		return HandlerFunc(func(req *http.Request) response.Response {
+			resp := inner.Handle(req)
+			builder := resp.ToBuilder()
+			h := builder.Headers()
+			for name, values := range hdrs {
+				if _, found := h[name]; found && !overwrite {
+					continue
+				}
+				h[name] = append([]string(nil), values...)
+			}
+			return *builder.Build()
+		})
+	}
+}
+
+// ErrorPages returns a Middleware which replaces every Response that carries
+// a Go error (see response.Response.Err) with an error page generated by the
+// given PageGenerator, which may be nil to use the Response's own.  The
+// replaced Response is closed.
+//
+// Responses with a 4xx or 5xx status keep their status; all others become
+// "500 Internal Server Error".
+//
+func ErrorPages(gen response.PageGenerator) Middleware {
+	return func(inner Handler) Handler {
+		return HandlerFunc(func(req *http.Request) response.Response {
+			resp := inner.Handle(req)
+			err := resp.Err()
+			if err == nil {
+				return resp
+			}
+
+			code := resp.Status()
+			if code < 400 || code > 599 {
+				code = http.StatusInternalServerError
+			}
+
+			g := gen
+			if g == nil {
+				g = resp.PageGenerator()
+			}
+			_ = resp.Close()
+
+			builder := response.NewBuilder().WithPageGenerator(g).WithRequestID(GetRequestID(req))
+			return *builder.ErrorPage(code, err).Build()
+		})
+	}
+}
+
+// MethodOverride returns a Middleware which lets POST requests carry an
+// X-HTTP-Method-Override header naming the method that the inner Handler
+// should see.  Only PUT, PATCH, and DELETE may be substituted.
+func MethodOverride() Middleware {
+	return func(inner Handler) Handler {
+		return HandlerFunc(func(req *http.Request) response.Response {
+			if req.Method == http.MethodPost {
+				switch method := strings.ToUpper(strings.TrimSpace(req.Header.Get("X-HTTP-Method-Override"))); method {
+				case http.MethodPut, http.MethodPatch, http.MethodDelete:
+					req = req.WithContext(req.Context())
+					req.Method = method
+				}
+			}
+			return inner.Handle(req)
+		})
+	}
+}
+
+// RequestID returns a Middleware which ensures that every request carries a
+// request ID in the named header, generating a random one if the client did
+// not supply a valid one, and copies the ID into the Response.  If name is
+// empty, DefaultRequestIDHeader is used.
+//
+// Adaptor.RequestID is usually a better choice, as it also makes the ID
+// available to access logs, panic reports, and Adaptor's own error pages.
+// When this Middleware runs under an Adaptor, it records the ID so that
+// GetRequestID returns it.
+//
+func RequestID(name string) Middleware {
+	opts := &RequestIDOptions{Header: name}
+	return func(inner Handler) Handler {
+		return HandlerFunc(func(req *http.Request) response.Response {
+			header := opts.header()
+			id := req.Header.Get(header)
+			if !opts.isValid(id) {
+				id = opts.generate()
+				req = req.WithContext(req.Context())
+				req.Header = req.Header.Clone()
+				if req.Header == nil {
+					req.Header = make(http.Header, 1)
+				}
+				req.Header.Set(header, id)
+			}
+			setRequestID(req, id)
+
+			resp := inner.Handle(req)
+			builder := resp.ToBuilder()
+			builder.WithHeader(header, id, false)
+			return *builder.Build()
+		})
+	}
+}
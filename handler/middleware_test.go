@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chronos-tachyon/morehttp/body"
+	"github.com/chronos-tachyon/morehttp/internal/mockreader"
+	"github.com/chronos-tachyon/morehttp/response"
+)
+
+func TestChain(t *testing.T) {
+	var order []string
+	record := func(name string) Middleware {
+		return func(inner Handler) Handler {
+			return HandlerFunc(func(req *http.Request) response.Response {
+				order = append(order, name)
+				return inner.Handle(req)
+			})
+		}
+	}
+
+	h := Chain(HandlerFunc(func(*http.Request) response.Response {
+		order = append(order, "inner")
+		return *response.NewBuilder().WithBody(body.Empty()).Build()
+	}), record("a"), record("b"))
+
+	resp := h.Handle(httptest.NewRequest(http.MethodGet, "/", nil))
+	_ = resp.Close()
+
+	if expect, actual := "[a b inner]", fmt.Sprint(order); expect != actual {
+		t.Errorf("expected order %s, got %s", expect, actual)
+	}
+}
+
+func TestInjectHeaders(t *testing.T) {
+	inner := HandlerFunc(func(*http.Request) response.Response {
+		return *response.NewBuilder().
+			WithHeader("X-Frame-Options", "SAMEORIGIN", false).
+			WithBody(body.FromString("abc")).
+			Build()
+	})
+
+	hdrs := http.Header{
+		"X-Frame-Options":        {"DENY"},
+		"X-Content-Type-Options": {"nosniff"},
+	}
+
+	for _, overwrite := range []bool{false, true} {
+		h := Chain(inner, InjectHeaders(hdrs, overwrite))
+		resp := h.Handle(httptest.NewRequest(http.MethodGet, "/", nil))
+
+		expect := "SAMEORIGIN"
+		if overwrite {
+			expect = "DENY"
+		}
+		if actual := resp.Headers().Get("X-Frame-Options"); expect != actual {
+			t.Errorf("overwrite=%v: expected X-Frame-Options %q, got %q", overwrite, expect, actual)
+		}
+		if expect, actual := "nosniff", resp.Headers().Get("X-Content-Type-Options"); expect != actual {
+			t.Errorf("overwrite=%v: expected X-Content-Type-Options %q, got %q", overwrite, expect, actual)
+		}
+		if expect, actual := "3", resp.Headers().Get("Content-Length"); expect != actual {
+			t.Errorf("overwrite=%v: expected Content-Length %q, got %q", overwrite, expect, actual)
+		}
+		_ = resp.Close()
+	}
+}
+
+func TestErrorPages(t *testing.T) {
+	errForPage := errors.New("database unavailable")
+
+	r := mockreader.New(
+		mockreader.ExpectClose(nil),
+		mockreader.ExpectMark("Handle-End"),
+	)
+
+	inner := HandlerFunc(func(*http.Request) response.Response {
+		b, err := body.FromReader(mockreader.Wrapper000{Inner: r})
+		if err != nil {
+			t.Fatalf("FromReader failed: %v", err)
+		}
+		return *response.NewBuilder().
+			WithStatus(http.StatusServiceUnavailable).
+			WithBody(b).
+			WithError(errForPage).
+			Build()
+	})
+
+	h := Chain(inner, ErrorPages(nil))
+	resp := h.Handle(httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// Panics if the original Body was not closed.
+	r.Mark("Handle-End")
+
+	if expect, actual := http.StatusServiceUnavailable, resp.Status(); expect != actual {
+		t.Errorf("expected status %d, got %d", expect, actual)
+	}
+	if expect, actual := errForPage, resp.Err(); expect != actual {
+		t.Errorf("expected error %v, got %v", expect, actual)
+	}
+
+	w := httptest.NewRecorder()
+	if err := resp.Serve(w); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+	if expect, actual := "503 Service Unavailable\r\n", w.Body.String(); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+}
+
+func TestErrorPages_RequestID(t *testing.T) {
+	inner := HandlerFunc(func(*http.Request) response.Response {
+		return *response.NewBuilder().
+			WithStatus(http.StatusServiceUnavailable).
+			WithBody(body.Empty()).
+			WithError(errors.New("database unavailable")).
+			Build()
+	})
+
+	a := Adaptor{
+		Inner:     Chain(inner, ErrorPages(nil)),
+		RequestID: &RequestIDOptions{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(DefaultRequestIDHeader, "req-503")
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, req)
+	if expect, actual := "503 Service Unavailable\r\nRequest ID: req-503\r\n", w.Body.String(); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+}
+
+func TestMethodOverride(t *testing.T) {
+	type testRow struct {
+		Method   string
+		Override string
+		Expect   string
+	}
+
+	testData := []testRow{
+		{http.MethodPost, "", http.MethodPost},
+		{http.MethodPost, "delete", http.MethodDelete},
+		{http.MethodPost, "PATCH", http.MethodPatch},
+		{http.MethodPost, "GET", http.MethodPost},
+		{http.MethodGet, "DELETE", http.MethodGet},
+	}
+
+	for _, row := range testData {
+		var actual string
+		h := Chain(HandlerFunc(func(req *http.Request) response.Response {
+			actual = req.Method
+			return *response.NewBuilder().WithBody(body.Empty()).Build()
+		}), MethodOverride())
+
+		req := httptest.NewRequest(row.Method, "/", nil)
+		if row.Override != "" {
+			req.Header.Set("X-HTTP-Method-Override", row.Override)
+		}
+		resp := h.Handle(req)
+		_ = resp.Close()
+
+		if actual != row.Expect {
+			t.Errorf("%s with override %q: expected %s, got %s", row.Method, row.Override, row.Expect, actual)
+		}
+		if req.Method != row.Method {
+			t.Errorf("%s with override %q: original request was modified", row.Method, row.Override)
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := Chain(HandlerFunc(func(req *http.Request) response.Response {
+		seen = req.Header.Get(DefaultRequestIDHeader)
+		return *response.NewBuilder().WithBody(body.Empty()).Build()
+	}), RequestID(""))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	resp := h.Handle(req)
+	if len(seen) != 32 {
+		t.Errorf("expected a generated 32-character request ID, got %q", seen)
+	}
+	if actual := resp.Headers().Get(DefaultRequestIDHeader); seen != actual {
+		t.Errorf("expected response request ID %q, got %q", seen, actual)
+	}
+	if actual := req.Header.Get(DefaultRequestIDHeader); actual != "" {
+		t.Errorf("original request was modified")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(DefaultRequestIDHeader, "abc123")
+	resp = h.Handle(req)
+	if expect, actual := "abc123", resp.Headers().Get(DefaultRequestIDHeader); expect != actual {
+		t.Errorf("expected response request ID %q, got %q", expect, actual)
+	}
+}
+
+func TestInjectHeaders_Stream(t *testing.T) {
+	inner := HandlerFunc(func(*http.Request) response.Response {
+		return *response.NewBuilder().
+			WithBody(body.FromFunc(func(w *body.PipeWriter) error {
+				_, err := w.Write([]byte("streamed"))
+				return err
+			})).
+			Build()
+	})
+
+	h := Chain(inner, InjectHeaders(http.Header{"X-Test": {"yes"}}, false))
+	resp := h.Handle(httptest.NewRequest(http.MethodGet, "/", nil))
+	dupe, err := resp.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	_ = resp.Close()
+
+	w := httptest.NewRecorder()
+	if err := dupe.ServeRequest(w, httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+		t.Fatalf("ServeRequest failed: %v", err)
+	}
+	if expect, actual := "yes", w.Header().Get("X-Test"); expect != actual {
+		t.Errorf("expected X-Test %q, got %q", expect, actual)
+	}
+	if expect, actual := "streamed", w.Body.String(); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+}
@@ -2,18 +2,56 @@ package handler
 
 import (
 	"fmt"
+	"runtime/debug"
 )
 
+// PanicError is the error which is reported when a Handler panics.
 type PanicError struct {
+	// Value is the value which was passed to panic().
 	Value interface{}
+
+	// Stack is the stack trace of the panicking goroutine, as formatted by
+	// runtime/debug.Stack.
+	Stack []byte
+
+	// RequestID is the ID of the request which was being served, if
+	// known.
+	RequestID string
 }
 
 func (err PanicError) Error() string {
 	return fmt.Sprintf("panic called with value of type %T: %+v", err.Value, err.Value)
 }
 
+// Unwrap returns Value if it is an error, or nil otherwise.
+func (err PanicError) Unwrap() error {
+	inner, _ := err.Value.(error)
+	return inner
+}
+
 var _ error = PanicError{}
 
+// newPanicError wraps a recovered panic value, capturing the current stack.
+// It must be called from the deferred function which recovered the panic.
+func newPanicError(panicValue interface{}) PanicError {
+	if err, ok := panicValue.(PanicError); ok {
+		return err
+	}
+	return PanicError{Value: panicValue, Stack: debug.Stack()}
+}
+
 func DefaultOnPanic(err error) {}
 
+// OnPanic is called by Adaptors which do not set their own OnPanic field.
+//
+// Deprecated: Set Adaptor.OnPanic instead.
+//
 var OnPanic func(error) = DefaultOnPanic
+
+func (a Adaptor) onPanic(err error) {
+	fn := a.OnPanic
+	if fn == nil {
+		fn = OnPanic
+	}
+	fn(err)
+}
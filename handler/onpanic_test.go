@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chronos-tachyon/morehttp/response"
+)
+
+var errForTest = errors.New("boom")
+
+func panickingHandlerForTest(req *http.Request) response.Response {
+	panic(errForTest)
+}
+
+func TestAdaptor_Panic(t *testing.T) {
+	var reported error
+	a := Adaptor{
+		Inner:   HandlerFunc(panickingHandlerForTest),
+		OnPanic: func(err error) { reported = err },
+	}
+
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if expect, actual := http.StatusInternalServerError, w.Code; expect != actual {
+		t.Errorf("expected status %d, got %d", expect, actual)
+	}
+	if expect, actual := "500 Internal Server Error\r\n", w.Body.String(); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+
+	var panicErr PanicError
+	if !errors.As(reported, &panicErr) {
+		t.Fatalf("expected PanicError, got %#v", reported)
+	}
+	if !errors.Is(reported, errForTest) {
+		t.Errorf("expected PanicError to unwrap to the panic value")
+	}
+	if !bytes.Contains(panicErr.Stack, []byte("panickingHandlerForTest")) {
+		t.Errorf("expected stack trace to mention the panicking function, got:\n%s", panicErr.Stack)
+	}
+}
+
+func TestAdaptor_PanicWithTimeout(t *testing.T) {
+	var reported error
+	a := Adaptor{
+		Inner:   HandlerFunc(panickingHandlerForTest),
+		OnPanic: func(err error) { reported = err },
+		Timeout: time.Minute,
+	}
+
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if expect, actual := http.StatusInternalServerError, w.Code; expect != actual {
+		t.Errorf("expected status %d, got %d", expect, actual)
+	}
+
+	var panicErr PanicError
+	if !errors.As(reported, &panicErr) {
+		t.Fatalf("expected PanicError, got %#v", reported)
+	}
+	if !bytes.Contains(panicErr.Stack, []byte("panickingHandlerForTest")) {
+		t.Errorf("expected stack trace to mention the panicking function, got:\n%s", panicErr.Stack)
+	}
+}
+
+func TestAdaptor_AbortHandler(t *testing.T) {
+	a := Adaptor{
+		Inner: HandlerFunc(func(*http.Request) response.Response {
+			panic(http.ErrAbortHandler)
+		}),
+		OnPanic: func(err error) {
+			t.Errorf("OnPanic must not be called for http.ErrAbortHandler")
+		},
+	}
+
+	defer func() {
+		if panicValue := recover(); panicValue != http.ErrAbortHandler {
+			t.Errorf("expected re-panic with http.ErrAbortHandler, got %#v", panicValue)
+		}
+	}()
+
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	t.Errorf("expected ServeHTTP to panic")
+}
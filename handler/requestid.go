@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// DefaultRequestIDHeader is the default value of RequestIDOptions.Header.
+const DefaultRequestIDHeader = "X-Request-Id"
+
+// DefaultRequestIDMaxLength is the default value of
+// RequestIDOptions.MaxLength.
+const DefaultRequestIDMaxLength = 128
+
+// RequestIDOptions configures request ID handling in an Adaptor.
+//
+// A request ID supplied by the client is used if it is valid; otherwise, a
+// new one is generated.  Either way, the ID is stored in the request context
+// (see GetRequestID), echoed in the response headers, included in access log
+// entries and PanicErrors, and passed to PageGenerators which implement
+// response.RequestIDPageGenerator.
+//
+type RequestIDOptions struct {
+	// Header is the name of the request and response header which carries
+	// the request ID.  If empty, DefaultRequestIDHeader is used.
+	Header string
+
+	// MaxLength is the longest client-supplied request ID which will be
+	// accepted.  If zero, DefaultRequestIDMaxLength is used.
+	MaxLength int
+
+	// Generate returns a new request ID.  If nil, RandomRequestID is used.
+	Generate func() string
+}
+
+func (opts *RequestIDOptions) header() string {
+	if opts.Header == "" {
+		return DefaultRequestIDHeader
+	}
+	return opts.Header
+}
+
+func (opts *RequestIDOptions) generate() string {
+	if opts.Generate == nil {
+		return RandomRequestID()
+	}
+	return opts.Generate()
+}
+
+// isValid reports whether a client-supplied request ID is acceptable.  Only
+// printable ASCII characters which cannot break log formats are allowed.
+func (opts *RequestIDOptions) isValid(id string) bool {
+	max := opts.MaxLength
+	if max == 0 {
+		max = DefaultRequestIDMaxLength
+	}
+	if id == "" || len(id) > max {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		ch := id[i]
+		switch {
+		case ch >= '0' && ch <= '9':
+		case ch >= 'A' && ch <= 'Z':
+		case ch >= 'a' && ch <= 'z':
+		case ch == '-' || ch == '_' || ch == '.' || ch == ':' || ch == '+' || ch == '/' || ch == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// resolve returns the request ID for the given request, generating a new one
+// if necessary.
+func (opts *RequestIDOptions) resolve(req *http.Request) string {
+	id := req.Header.Get(opts.header())
+	if opts.isValid(id) {
+		return id
+	}
+	return opts.generate()
+}
+
+// GetRequestID returns the request ID assigned to the given request by
+// Adaptor or by the RequestID Middleware, or the empty string if none.
+func GetRequestID(req *http.Request) string {
+	state := getRequestState(req.Context())
+	if state == nil {
+		return ""
+	}
+	return state.getRequestID()
+}
+
+func setRequestID(req *http.Request, id string) {
+	state := getRequestState(req.Context())
+	if state == nil {
+		return
+	}
+
+	state.mu.Lock()
+	state.requestID = id
+	state.mu.Unlock()
+}
+
+// RandomRequestID generates a random 128-bit request ID, formatted as 32
+// hexadecimal digits.
+func RandomRequestID() string {
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(raw[:])
+}
+
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDRequestID generates a request ID in ULID format: a 48-bit millisecond
+// timestamp followed by 80 random bits, as 26 Crockford base-32 digits.  Such
+// IDs sort by creation time.
+func ULIDRequestID() string {
+	var raw [16]byte
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], ms)
+	copy(raw[0:6], ts[2:8])
+	if _, err := rand.Read(raw[6:]); err != nil {
+		panic(err)
+	}
+
+	// 128 bits = 26 digits of 5 bits each, with the first digit holding
+	// only the top 3 bits.
+	var out [26]byte
+	hi := binary.BigEndian.Uint64(raw[0:8])
+	lo := binary.BigEndian.Uint64(raw[8:16])
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordBase32[lo&0x1f]
+		lo = (lo >> 5) | (hi << 59)
+		hi >>= 5
+	}
+	return string(out[:])
+}
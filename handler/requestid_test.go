@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chronos-tachyon/morehttp/body"
+	"github.com/chronos-tachyon/morehttp/response"
+)
+
+func TestAdaptor_RequestID(t *testing.T) {
+	var seen string
+	a := Adaptor{
+		RequestID: &RequestIDOptions{
+			Header:    "X-Trace",
+			MaxLength: 16,
+			Generate:  func() string { return "generated" },
+		},
+		Inner: HandlerFunc(func(req *http.Request) response.Response {
+			seen = GetRequestID(req)
+			return *response.NewBuilder().WithBody(body.Empty()).Build()
+		}),
+	}
+
+	type testRow struct {
+		Incoming string
+		Expect   string
+	}
+
+	testData := []testRow{
+		{"", "generated"},
+		{"abc-123_XYZ", "abc-123_XYZ"},
+		{"has space", "generated"},
+		{"much-too-long-for-the-limit", "generated"},
+		{`quote"`, "generated"},
+	}
+
+	for _, row := range testData {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if row.Incoming != "" {
+			req.Header.Set("X-Trace", row.Incoming)
+		}
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, req)
+
+		if seen != row.Expect {
+			t.Errorf("%q: expected request ID %q, got %q", row.Incoming, row.Expect, seen)
+		}
+		if actual := w.Header().Get("X-Trace"); actual != row.Expect {
+			t.Errorf("%q: expected echoed request ID %q, got %q", row.Incoming, row.Expect, actual)
+		}
+	}
+}
+
+func TestAdaptor_RequestIDOnServeTimeErrorPage(t *testing.T) {
+	a := Adaptor{
+		RequestID: &RequestIDOptions{},
+		Inner: HandlerFunc(func(req *http.Request) response.Response {
+			return *response.NewBuilder().WithETag("v1", true).WithBody(body.FromString("content")).Build()
+		}),
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/", nil)
+	req.Header.Set(DefaultRequestIDHeader, "req-412")
+	req.Header.Set("If-Match", `"v2"`)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, req)
+	if expect, actual := "412 Precondition Failed\r\nRequest ID: req-412\r\n", w.Body.String(); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+}
+
+func TestAdaptor_RequestIDOnPanic(t *testing.T) {
+	var reported error
+	a := Adaptor{
+		RequestID: &RequestIDOptions{},
+		OnPanic:   func(err error) { reported = err },
+		Inner:     HandlerFunc(panickingHandlerForTest),
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(DefaultRequestIDHeader, "support-me")
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, req)
+
+	if expect, actual := "500 Internal Server Error\r\nRequest ID: support-me\r\n", w.Body.String(); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+
+	var panicErr PanicError
+	if !errors.As(reported, &panicErr) {
+		t.Fatalf("expected PanicError, got %#v", reported)
+	}
+	if expect, actual := "support-me", panicErr.RequestID; expect != actual {
+		t.Errorf("expected PanicError.RequestID %q, got %q", expect, actual)
+	}
+}
+
+func TestRequestIDGenerators(t *testing.T) {
+	id := RandomRequestID()
+	if len(id) != 32 || strings.Trim(id, "0123456789abcdef") != "" {
+		t.Errorf("RandomRequestID: unexpected format %q", id)
+	}
+
+	a, b := ULIDRequestID(), ULIDRequestID()
+	for _, id := range []string{a, b} {
+		if len(id) != 26 || strings.Trim(id, crockfordBase32) != "" || id[0] > '7' {
+			t.Errorf("ULIDRequestID: unexpected format %q", id)
+		}
+	}
+	if a[:8] > b[:8] {
+		t.Errorf("ULIDRequestID: expected time-ordered IDs, got %q then %q", a, b)
+	}
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/chronos-tachyon/morehttp/response"
+)
+
+// requestState holds the mutable per-request data which Adaptor makes
+// available to the code that it wraps.
+type requestState struct {
+	mu        sync.Mutex
+	route     string
+	requestID string
+}
+
+type requestStateKey struct{}
+
+func withRequestState(req *http.Request) (*http.Request, *requestState) {
+	state := &requestState{}
+	ctx := context.WithValue(req.Context(), requestStateKey{}, state)
+	ctx = response.ContextWithRequestIDFunc(ctx, state.getRequestID)
+	return req.WithContext(ctx), state
+}
+
+func (state *requestState) getRequestID() string {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.requestID
+}
+
+func getRequestState(ctx context.Context) *requestState {
+	state, _ := ctx.Value(requestStateKey{}).(*requestState)
+	return state
+}
+
+// SetRouteName records the name of the route which matched the given request,
+// for use in metrics and logs.  It has no effect if the request is not being
+// served by an Adaptor.
+func SetRouteName(req *http.Request, name string) {
+	state := getRequestState(req.Context())
+	if state == nil {
+		return
+	}
+
+	state.mu.Lock()
+	state.route = name
+	state.mu.Unlock()
+}
+
+// RouteName returns the route name previously recorded by SetRouteName, or the
+// empty string if none has been recorded.
+func RouteName(req *http.Request) string {
+	state := getRequestState(req.Context())
+	if state == nil {
+		return ""
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.route
+}
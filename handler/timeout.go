@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chronos-tachyon/morehttp/response"
+)
+
+// ErrHandlerTimeout is the error which is passed to the PageGenerator when an
+// Adaptor's Timeout expires before its Handler returns.
+var ErrHandlerTimeout = errors.New("handler did not return a response before its deadline")
+
+// handleContext is the request context seen by Inner.Handle when Timeout is
+// set.  It behaves like the context returned by context.WithTimeout, except
+// that the deadline can be lifted once Handle has returned in time.  This
+// matters because a streaming Body may capture the request context and keep
+// using it long after Handle returns.
+type handleContext struct {
+	context.Context
+	cancel   context.CancelFunc
+	timer    *time.Timer
+	deadline time.Time
+
+	mu      sync.Mutex
+	expired bool
+	lifted  bool
+}
+
+func newHandleContext(parent context.Context, timeout time.Duration) *handleContext {
+	inner, cancel := context.WithCancel(parent)
+	ctx := &handleContext{
+		Context:  inner,
+		cancel:   cancel,
+		deadline: time.Now().Add(timeout),
+	}
+	ctx.timer = time.AfterFunc(timeout, ctx.expire)
+	return ctx
+}
+
+func (ctx *handleContext) Deadline() (time.Time, bool) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	parent, ok := ctx.Context.Deadline()
+	if ctx.lifted || (ok && parent.Before(ctx.deadline)) {
+		return parent, ok
+	}
+	return ctx.deadline, true
+}
+
+func (ctx *handleContext) Err() error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	if ctx.expired {
+		return context.DeadlineExceeded
+	}
+	return ctx.Context.Err()
+}
+
+func (ctx *handleContext) expire() {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	if ctx.lifted || ctx.Context.Err() != nil {
+		return
+	}
+	ctx.expired = true
+	ctx.cancel()
+}
+
+// timedOut reports whether the deadline expired while the parent context was
+// still live.
+func (ctx *handleContext) timedOut() bool {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return ctx.expired
+}
+
+// lift removes the deadline, unless it has already expired.
+func (ctx *handleContext) lift() {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	if !ctx.expired {
+		ctx.timer.Stop()
+		ctx.lifted = true
+	}
+}
+
+// release frees the resources associated with the context.
+func (ctx *handleContext) release() {
+	ctx.timer.Stop()
+	ctx.cancel()
+}
+
+type handleResult struct {
+	resp       response.Response
+	panicValue interface{}
+	panicked   bool
+}
+
+// handleWithTimeout calls a.Inner.Handle in a separate goroutine, waiting at
+// most a.Timeout for it to return.  The returned bool is true iff the
+// deadline expired first, in which case the Response is a generated error
+// page and the late Response, if any, is closed when it arrives.  A panic in
+// the Handler is propagated to the caller, or counted and reported to OnPanic
+// if it happens after the deadline.
+//
+// If the request is cancelled for any other reason, such as the client going
+// away, then the Handler sees the cancellation and its Response is used as
+// usual.
+//
+func (a Adaptor) handleWithTimeout(ctx *handleContext, req *http.Request, m *Metrics) (response.Response, bool) {
+	ch := make(chan handleResult, 1)
+	go func() {
+		var result handleResult
+		defer func() {
+			if panicValue := recover(); panicValue != nil {
+				if panicValue != http.ErrAbortHandler {
+					panicValue = newPanicError(panicValue)
+				}
+				result.panicValue = panicValue
+				result.panicked = true
+			}
+			ch <- result
+		}()
+		result.resp = a.Inner.Handle(req)
+	}()
+
+	var result handleResult
+	select {
+	case result = <-ch:
+	case <-ctx.Done():
+		if ctx.timedOut() {
+			go a.finishLate(ch, req, m)
+			return a.timeoutPage(req), true
+		}
+		result = <-ch
+	}
+
+	if result.panicked {
+		panic(result.panicValue)
+	}
+	return result.resp, false
+}
+
+// finishLate waits for a Handler which missed its deadline.  Its Response is
+// closed unused, and a panic is counted and reported as usual, except that
+// nothing is re-panicked, as the request has already been served.
+func (a Adaptor) finishLate(ch <-chan handleResult, req *http.Request, m *Metrics) {
+	result := <-ch
+	if !result.panicked {
+		_ = result.resp.Close()
+		return
+	}
+
+	err, ok := result.panicValue.(PanicError)
+	if !ok {
+		err = PanicError{Value: result.panicValue}
+	}
+	err.RequestID = GetRequestID(req)
+	m.observePanic(m.labels(req, a.Name))
+	a.onPanic(err)
+}
+
+func (a Adaptor) timeoutPage(req *http.Request) response.Response {
+	code := a.TimeoutCode
+	if code == 0 {
+		code = http.StatusServiceUnavailable
+	}
+
+	return *a.newBuilder(req).ErrorPage(code, ErrHandlerTimeout).Build()
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/chronos-tachyon/morehttp/body"
+	"github.com/chronos-tachyon/morehttp/response"
+)
+
+func TestAdaptor_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	closed := make(chan struct{})
+
+	r := &notifyCloser{Reader: strings.NewReader("late"), ch: closed}
+
+	a := Adaptor{
+		Timeout:     10 * time.Millisecond,
+		TimeoutCode: http.StatusGatewayTimeout,
+		Inner: HandlerFunc(func(req *http.Request) response.Response {
+			if _, ok := req.Context().Deadline(); !ok {
+				t.Errorf("expected request context to have a deadline")
+			}
+			<-req.Context().Done()
+			if expect, actual := context.DeadlineExceeded, req.Context().Err(); expect != actual {
+				t.Errorf("expected context error %v, got %v", expect, actual)
+			}
+			<-release
+			b, err := body.FromReader(r)
+			if err != nil {
+				t.Errorf("FromReader failed: %v", err)
+			}
+			return *response.NewBuilder().WithBody(b).Build()
+		}),
+	}
+
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if expect, actual := http.StatusGatewayTimeout, w.Code; expect != actual {
+		t.Errorf("expected status %d, got %d", expect, actual)
+	}
+
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Errorf("late Response was never closed")
+	}
+}
+
+func TestAdaptor_TimeoutNotReached(t *testing.T) {
+	a := Adaptor{
+		Timeout: time.Minute,
+		Inner: HandlerFunc(func(req *http.Request) response.Response {
+			if err := req.Context().Err(); err != nil {
+				t.Errorf("unexpected context error: %v", err)
+			}
+			return *response.NewBuilder().WithBody(body.FromString("ok")).Build()
+		}),
+	}
+
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if expect, actual := http.StatusOK, w.Code; expect != actual {
+		t.Errorf("expected status %d, got %d", expect, actual)
+	}
+	if expect, actual := "ok", w.Body.String(); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+}
+
+func TestAdaptor_TimeoutStreamOutlivesDeadline(t *testing.T) {
+	const timeout = 20 * time.Millisecond
+
+	a := Adaptor{
+		Timeout: timeout,
+		Inner: HandlerFunc(func(req *http.Request) response.Response {
+			s := response.NewEventStream(req, &response.EventStreamOptions{Heartbeat: -1})
+			go func() {
+				defer s.Close()
+				for _, data := range []string{"a", "b"} {
+					time.Sleep(2 * timeout)
+					if err := s.Send(response.Event{Data: data}); err != nil {
+						t.Errorf("Send failed: %v", err)
+						return
+					}
+				}
+			}()
+			return *response.NewBuilder().WithEventStream(s).Build()
+		}),
+	}
+
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if expect, actual := http.StatusOK, w.Code; expect != actual {
+		t.Errorf("expected status %d, got %d", expect, actual)
+	}
+	if expect, actual := "data: a\n\ndata: b\n\n", w.Body.String(); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+}
+
+func TestAdaptor_TimeoutClientCancel(t *testing.T) {
+	m, err := NewMetrics(MetricsOptions{Registerer: prometheus.NewRegistry()})
+	if err != nil {
+		t.Fatalf("NewMetrics failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := Adaptor{
+		Metrics: m,
+		Timeout: time.Minute,
+		Inner: HandlerFunc(func(req *http.Request) response.Response {
+			// The client goes away long before the deadline.
+			cancel()
+			<-req.Context().Done()
+			if expect, actual := context.Canceled, req.Context().Err(); expect != actual {
+				t.Errorf("expected context error %v, got %v", expect, actual)
+			}
+			return *response.NewBuilder().WithBody(body.FromString("cancelled")).Build()
+		}),
+	}
+
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	if expect, actual := http.StatusOK, w.Code; expect != actual {
+		t.Errorf("expected status %d, got %d", expect, actual)
+	}
+	if expect, actual := "cancelled", w.Body.String(); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+	if actual := testutil.ToFloat64(m.timeouts.with(prometheus.Labels{})); actual != 0 {
+		t.Errorf("expected 0 timeouts, got %v", actual)
+	}
+}
+
+func TestAdaptor_TimeoutLatePanic(t *testing.T) {
+	for _, value := range []interface{}{errForTest, http.ErrAbortHandler} {
+		m, err := NewMetrics(MetricsOptions{Registerer: prometheus.NewRegistry()})
+		if err != nil {
+			t.Fatalf("NewMetrics failed: %v", err)
+		}
+
+		release := make(chan struct{})
+		reported := make(chan error, 1)
+
+		a := Adaptor{
+			Metrics: m,
+			Timeout: 10 * time.Millisecond,
+			OnPanic: func(err error) { reported <- err },
+			Inner: HandlerFunc(func(req *http.Request) response.Response {
+				<-req.Context().Done()
+				<-release
+				panic(value)
+			}),
+		}
+
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if expect, actual := http.StatusServiceUnavailable, w.Code; expect != actual {
+			t.Errorf("%v: expected status %d, got %d", value, expect, actual)
+		}
+
+		close(release)
+		select {
+		case err := <-reported:
+			var panicErr PanicError
+			if !errors.As(err, &panicErr) || panicErr.Value != value {
+				t.Errorf("%v: expected PanicError for %v, got %v", value, value, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("%v: late panic was never reported", value)
+			continue
+		}
+		if actual := testutil.ToFloat64(m.panics.with(prometheus.Labels{})); actual != 1 {
+			t.Errorf("%v: expected 1 panic, got %v", value, actual)
+		}
+	}
+}
+
+type notifyCloser struct {
+	io.Reader
+	ch chan struct{}
+}
+
+func (r *notifyCloser) Close() error {
+	close(r.ch)
+	return nil
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/chronos-tachyon/morehttp/tracing"
+)
+
+// Span attribute keys, following the OpenTelemetry semantic conventions for
+// HTTP servers.
+const (
+	AttrHTTPMethod       = "http.request.method"
+	AttrHTTPRoute        = "http.route"
+	AttrHTTPStatusCode   = "http.response.status_code"
+	AttrHTTPRequestSize  = "http.request.body.size"
+	AttrHTTPResponseSize = "http.response.body.size"
+	AttrURLPath          = "url.path"
+)
+
+// finishSpan records the outcome of the request on its server span and ends
+// the span.  Following the HTTP server conventions, the span's status is only
+// set to StatusError for 5xx responses; any other error is recorded as an
+// event without marking the span as failed.  A panic always marks the span as
+// failed, even if a successful status was already sent.
+func finishSpan(span tracing.Span, req *http.Request, status int, recvBodyBytes int64, sendBytes int64, err error, panicked bool) {
+	route := RouteName(req)
+	if route != "" {
+		span.SetName(req.Method + " " + route)
+		span.SetAttribute(AttrHTTPRoute, route)
+	}
+	span.SetAttribute(AttrHTTPMethod, req.Method)
+	span.SetAttribute(AttrURLPath, req.URL.Path)
+	span.SetAttribute(AttrHTTPStatusCode, int64(status))
+	span.SetAttribute(AttrHTTPRequestSize, recvBodyBytes)
+	span.SetAttribute(AttrHTTPResponseSize, sendBytes)
+
+	if err != nil {
+		span.RecordError(err)
+	}
+	if panicked || status >= 500 {
+		description := http.StatusText(status)
+		if err != nil {
+			description = err.Error()
+		}
+		span.SetStatus(tracing.StatusError, description)
+	}
+	span.End()
+}
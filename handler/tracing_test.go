@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/chronos-tachyon/morehttp/body"
+	"github.com/chronos-tachyon/morehttp/response"
+	"github.com/chronos-tachyon/morehttp/tracing"
+)
+
+func TestAdaptor_Tracer(t *testing.T) {
+	tracer := tracing.NewMemoryTracer()
+
+	var inner tracing.SpanContext
+	a := Adaptor{
+		Tracer: tracer,
+		// The span's request size must not include the header estimate.
+		CountHeaderBytes: true,
+		Inner: HandlerFunc(func(req *http.Request) response.Response {
+			SetRouteName(req, "/items/{id}")
+			inner = tracing.SpanContextFromContext(req.Context())
+			_, _ = io.ReadAll(req.Body)
+			return *response.NewBuilder().WithBody(body.FromString("hello")).Build()
+		}),
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/items/7", strings.NewReader("abc"))
+	req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("Tracestate", "congo=t61rcWkgMzE")
+	a.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := tracer.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+
+	if expect, actual := "POST /items/{id}", span.Name(); expect != actual {
+		t.Errorf("expected name %q, got %q", expect, actual)
+	}
+	if expect, actual := "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID.String(); expect != actual {
+		t.Errorf("expected TraceID %q, got %q", expect, actual)
+	}
+	if expect, actual := "00f067aa0ba902b7", span.Parent().SpanID.String(); expect != actual {
+		t.Errorf("expected parent SpanID %q, got %q", expect, actual)
+	}
+	if expect, actual := "congo=t61rcWkgMzE", span.SpanContext().TraceState; expect != actual {
+		t.Errorf("expected TraceState %q, got %q", expect, actual)
+	}
+	if inner != span.SpanContext() {
+		t.Errorf("expected Inner to see span %v, got %v", span.SpanContext(), inner)
+	}
+
+	expectAttrs := map[string]interface{}{
+		AttrHTTPMethod:       "POST",
+		AttrHTTPRoute:        "/items/{id}",
+		AttrURLPath:          "/items/7",
+		AttrHTTPStatusCode:   int64(200),
+		AttrHTTPRequestSize:  int64(3),
+		AttrHTTPResponseSize: int64(5),
+	}
+	for key, expect := range expectAttrs {
+		if actual, _ := span.Attribute(key); actual != expect {
+			t.Errorf("attribute %q: expected %#v, got %#v", key, expect, actual)
+		}
+	}
+	if code, _ := span.Status(); code != tracing.StatusUnset {
+		t.Errorf("expected StatusUnset, got %v", code)
+	}
+}
+
+func TestAdaptor_TracerErrors(t *testing.T) {
+	tracer := tracing.NewMemoryTracer()
+
+	errNotFound := errors.New("no such item")
+	a := Adaptor{
+		Tracer: tracer,
+		Inner: HandlerFunc(func(req *http.Request) response.Response {
+			return *response.NewBuilder().ErrorPage(http.StatusNotFound, errNotFound).Build()
+		}),
+	}
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	a.Inner = HandlerFunc(panickingHandlerForTest)
+	a.OnPanic = func(error) {}
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	spans := tracer.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	if errs := spans[0].Errors(); len(errs) != 1 || !errors.Is(errs[0], errNotFound) {
+		t.Errorf("expected errors [%v], got %v", errNotFound, errs)
+	}
+	if code, _ := spans[0].Status(); code != tracing.StatusUnset {
+		t.Errorf("expected StatusUnset for a 4xx response, got %v", code)
+	}
+	if spans[0].Parent().IsValid() {
+		t.Errorf("expected no parent, got %v", spans[0].Parent())
+	}
+
+	if errs := spans[1].Errors(); len(errs) != 1 || !errors.Is(errs[0], errForTest) {
+		t.Errorf("expected errors [%v], got %v", errForTest, errs)
+	}
+	if actual, _ := spans[1].Attribute(AttrHTTPStatusCode); actual != int64(500) {
+		t.Errorf("expected status 500, got %v", actual)
+	}
+	if code, _ := spans[1].Status(); code != tracing.StatusError {
+		t.Errorf("expected StatusError for a 5xx response, got %v", code)
+	}
+}
+
+func TestAdaptor_TracerPanicAfterWriteHeader(t *testing.T) {
+	tracer := tracing.NewMemoryTracer()
+
+	a := Adaptor{
+		Tracer:  tracer,
+		OnPanic: func(error) {},
+		Inner: HandlerFunc(func(req *http.Request) response.Response {
+			// The Body fails after the 200 status has been sent, which
+			// makes ServeHTTP panic.
+			r := io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(errForTest))
+			b, err := body.FromReader(r)
+			if err != nil {
+				t.Fatalf("FromReader failed: %v", err)
+			}
+			return *response.NewBuilder().WithBody(b).Build()
+		}),
+	}
+
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if expect, actual := http.StatusOK, w.Code; expect != actual {
+		t.Fatalf("expected status %d, got %d", expect, actual)
+	}
+
+	spans := tracer.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if code, _ := spans[0].Status(); code != tracing.StatusError {
+		t.Errorf("expected StatusError after a panic, got %v", code)
+	}
+	if errs := spans[0].Errors(); len(errs) != 1 || !errors.Is(errs[0], errForTest) {
+		t.Errorf("expected errors [%v], got %v", errForTest, errs)
+	}
+}
@@ -0,0 +1,209 @@
+// Package negotiate implements the proactive content negotiation rules for
+// the Accept family of HTTP request headers.
+package negotiate
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Item represents one element of an Accept-style header.
+type Item struct {
+	// Value is the lower-cased media range, content coding, or similar.
+	Value string
+
+	// Params holds the parameters which preceded the "q" parameter, with
+	// lower-cased names.
+	Params map[string]string
+
+	// Q is the quality value, between 0 and 1 inclusive.
+	Q float64
+}
+
+// Parse parses the given values of an Accept-style header.  Malformed elements
+// are skipped.
+func Parse(values []string) []Item {
+	var list []Item
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			item, ok := parseItem(element)
+			if ok {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+func parseItem(str string) (Item, bool) {
+	pieces := strings.Split(str, ";")
+	value := strings.ToLower(strings.TrimSpace(pieces[0]))
+	if value == "" {
+		return Item{}, false
+	}
+
+	item := Item{Value: value, Q: 1.0}
+	for _, piece := range pieces[1:] {
+		piece = strings.TrimSpace(piece)
+		if piece == "" {
+			continue
+		}
+
+		var k, v string
+		if i := strings.IndexByte(piece, '='); i >= 0 {
+			k = strings.ToLower(strings.TrimSpace(piece[:i]))
+			v = strings.Trim(strings.TrimSpace(piece[i+1:]), `"`)
+		} else {
+			k = strings.ToLower(piece)
+		}
+
+		if k == "q" {
+			q, err := strconv.ParseFloat(v, 64)
+			if err != nil || q < 0.0 || q > 1.0 {
+				return Item{}, false
+			}
+			item.Q = q
+			break
+		}
+
+		if item.Params == nil {
+			item.Params = make(map[string]string, 4)
+		}
+		item.Params[k] = v
+	}
+	return item, true
+}
+
+func normalizeEncoding(str string) string {
+	str = strings.ToLower(strings.TrimSpace(str))
+	switch str {
+	case "x-gzip":
+		return "gzip"
+	case "x-compress":
+		return "compress"
+	default:
+		return str
+	}
+}
+
+// SelectEncoding chooses a content coding using the given values of the
+// Accept-Encoding header.  A nil slice means that the header was absent.
+//
+// The available codings are listed in order of server preference, which is
+// used to break ties.  The "identity" coding is only chosen if it is listed
+// among the available codings.
+//
+// Returns the chosen coding and true, or the empty string and false if none
+// of the available codings are acceptable.
+//
+func SelectEncoding(values []string, available []string) (string, bool) {
+	if values == nil {
+		for _, name := range available {
+			if normalizeEncoding(name) == "identity" {
+				return name, true
+			}
+		}
+		if len(available) > 0 {
+			return available[0], true
+		}
+		return "", false
+	}
+
+	items := Parse(values)
+
+	var (
+		best  string
+		bestQ float64
+	)
+	for _, name := range available {
+		q := encodingQ(items, normalizeEncoding(name))
+		if q > bestQ {
+			best = name
+			bestQ = q
+		}
+	}
+	return best, bestQ > 0.0
+}
+
+func encodingQ(items []Item, name string) float64 {
+	starQ := -1.0
+	for _, item := range items {
+		value := normalizeEncoding(item.Value)
+		if value == name {
+			return item.Q
+		}
+		if value == "*" {
+			starQ = item.Q
+		}
+	}
+	if starQ >= 0.0 {
+		return starQ
+	}
+	if name == "identity" {
+		// Identity is always acceptable unless explicitly refused.
+		return 0.001
+	}
+	return 0.0
+}
+
+// SelectMediaType chooses a media type using the given values of the Accept
+// header.  A nil slice means that the header was absent.
+//
+// The available media types are listed in order of server preference, which
+// is used to break ties.  Each must be of the form "type/subtype", without
+// parameters.
+//
+// Returns the chosen media type and true, or the empty string and false if
+// none of the available media types are acceptable.
+//
+func SelectMediaType(values []string, available []string) (string, bool) {
+	if values == nil {
+		if len(available) > 0 {
+			return available[0], true
+		}
+		return "", false
+	}
+
+	items := Parse(values)
+
+	var (
+		best  string
+		bestQ float64
+	)
+	for _, mediaType := range available {
+		q := mediaTypeQ(items, strings.ToLower(mediaType))
+		if q > bestQ {
+			best = mediaType
+			bestQ = q
+		}
+	}
+	return best, bestQ > 0.0
+}
+
+func mediaTypeQ(items []Item, mediaType string) float64 {
+	major := mediaType
+	if i := strings.IndexByte(mediaType, '/'); i >= 0 {
+		major = mediaType[:i]
+	}
+
+	bestSpecificity := -1
+	bestQ := 0.0
+	for _, item := range items {
+		var specificity int
+		switch {
+		case item.Value == mediaType:
+			specificity = 2
+		case item.Value == major+"/*":
+			specificity = 1
+		case item.Value == "*/*":
+			specificity = 0
+		default:
+			continue
+		}
+		if specificity > bestSpecificity {
+			bestSpecificity = specificity
+			bestQ = item.Q
+		}
+	}
+	return bestQ
+}
@@ -0,0 +1,67 @@
+package negotiate
+
+import (
+	"testing"
+)
+
+func TestSelectEncoding(t *testing.T) {
+	available := []string{"br", "gzip", "deflate", "identity"}
+
+	type testRow struct {
+		Values []string
+		Expect string
+		OK     bool
+	}
+
+	testData := []testRow{
+		{nil, "identity", true},
+		{[]string{""}, "identity", true},
+		{[]string{"gzip"}, "gzip", true},
+		{[]string{"x-gzip"}, "gzip", true},
+		{[]string{"gzip, deflate, br"}, "br", true},
+		{[]string{"gzip;q=1.0, br;q=0.5"}, "gzip", true},
+		{[]string{"deflate;q=0.5", "gzip;q=0.8"}, "gzip", true},
+		{[]string{"*"}, "br", true},
+		{[]string{"*;q=0.5, br;q=0"}, "gzip", true},
+		{[]string{"compress"}, "identity", true},
+		{[]string{"identity;q=0"}, "", false},
+		{[]string{"*;q=0"}, "", false},
+		{[]string{"gzip;q=bogus"}, "identity", true},
+	}
+
+	for _, row := range testData {
+		actual, ok := SelectEncoding(row.Values, available)
+		if actual != row.Expect || ok != row.OK {
+			t.Errorf("SelectEncoding(%q): expected (%q, %v), got (%q, %v)", row.Values, row.Expect, row.OK, actual, ok)
+		}
+	}
+}
+
+func TestSelectMediaType(t *testing.T) {
+	available := []string{"application/json", "application/vnd.google.protobuf", "text/plain"}
+
+	type testRow struct {
+		Values []string
+		Expect string
+		OK     bool
+	}
+
+	testData := []testRow{
+		{nil, "application/json", true},
+		{[]string{"*/*"}, "application/json", true},
+		{[]string{"text/plain"}, "text/plain", true},
+		{[]string{"text/*"}, "text/plain", true},
+		{[]string{"application/*;q=0.5, text/plain"}, "text/plain", true},
+		{[]string{"application/vnd.google.protobuf, application/json;q=0.9"}, "application/vnd.google.protobuf", true},
+		{[]string{"*/*;q=0.1, application/json;q=0"}, "application/vnd.google.protobuf", true},
+		{[]string{"text/html; charset=utf-8"}, "", false},
+		{[]string{"image/*"}, "", false},
+	}
+
+	for _, row := range testData {
+		actual, ok := SelectMediaType(row.Values, available)
+		if actual != row.Expect || ok != row.OK {
+			t.Errorf("SelectMediaType(%q): expected (%q, %v), got (%q, %v)", row.Values, row.Expect, row.OK, actual, ok)
+		}
+	}
+}
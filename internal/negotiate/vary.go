@@ -0,0 +1,20 @@
+package negotiate
+
+import (
+	"net/http"
+	"strings"
+)
+
+// AddVary adds the named request header to the Vary response header, unless
+// it is already listed or Vary is "*".
+func AddVary(h http.Header, name string) {
+	for _, value := range h.Values("Vary") {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item == "*" || strings.EqualFold(item, name) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", name)
+}
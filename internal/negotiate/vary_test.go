@@ -0,0 +1,33 @@
+package negotiate
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAddVary(t *testing.T) {
+	type testRow struct {
+		Values []string
+		Expect string
+	}
+
+	testData := []testRow{
+		{nil, "Accept-Encoding"},
+		{[]string{"Accept"}, "Accept, Accept-Encoding"},
+		{[]string{"accept-encoding"}, "accept-encoding"},
+		{[]string{"Accept, Accept-Encoding"}, "Accept, Accept-Encoding"},
+		{[]string{"*"}, "*"},
+	}
+
+	for _, row := range testData {
+		h := make(http.Header)
+		for _, value := range row.Values {
+			h.Add("Vary", value)
+		}
+		AddVary(h, "Accept-Encoding")
+		if actual := strings.Join(h.Values("Vary"), ", "); row.Expect != actual {
+			t.Errorf("%q: expected %q, got %q", row.Values, row.Expect, actual)
+		}
+	}
+}
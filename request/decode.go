@@ -0,0 +1,269 @@
+package request
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/chronos-tachyon/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultMaxBytes is the default value of Options.MaxBytes.
+const DefaultMaxBytes = 1 << 20
+
+// Options controls the behavior of the Decode functions.
+//
+// A nil *Options is equivalent to a pointer to the zero value.
+//
+type Options struct {
+	// MaxBytes is the largest request body which will be accepted, as
+	// measured both before and after any Content-Encoding is removed.  If
+	// zero, DefaultMaxBytes is used; if negative, there is no limit.
+	MaxBytes int64
+
+	// DisallowUnknownFields causes JSON and text protobuf decoding to fail
+	// when the input contains fields that are not present in the
+	// destination.
+	DisallowUnknownFields bool
+}
+
+func (opts *Options) maxBytes() int64 {
+	if opts == nil || opts.MaxBytes == 0 {
+		return DefaultMaxBytes
+	}
+	return opts.MaxBytes
+}
+
+func (opts *Options) disallowUnknownFields() bool {
+	return opts != nil && opts.DisallowUnknownFields
+}
+
+var (
+	jsonMediaTypes      = []string{"application/json", "application/*+json"}
+	protoMediaTypes     = []string{"application/vnd.google.protobuf", "application/x-protobuf", "application/protobuf"}
+	protoTextMediaTypes = []string{"text/plain"}
+	formMediaTypes      = []string{"application/x-www-form-urlencoded"}
+)
+
+// DecodeJSON reads the request body as JSON and stores it in v.
+//
+// If v is a proto.Message, then it is decoded using the canonical protobuf
+// JSON mapping.  Otherwise, encoding/json is used.
+//
+// The request's Content-Type must be "application/json" or another JSON-based
+// media type.
+//
+func DecodeJSON(req *http.Request, v interface{}, opts *Options) error {
+	raw, err := readBody(req, jsonMediaTypes, opts)
+	if err != nil {
+		return err
+	}
+
+	if msg, ok := v.(proto.Message); ok {
+		o := protojson.UnmarshalOptions{DiscardUnknown: !opts.disallowUnknownFields()}
+		if err := o.Unmarshal(raw, msg); err != nil {
+			return MalformedError{err}
+		}
+		return nil
+	}
+
+	d := json.NewDecoder(bytes.NewReader(raw))
+	if opts.disallowUnknownFields() {
+		d.DisallowUnknownFields()
+	}
+	if err := d.Decode(v); err != nil {
+		return MalformedError{err}
+	}
+	if d.More() {
+		return MalformedError{errors.New("unexpected data after JSON value")}
+	}
+	return nil
+}
+
+// DecodeProto reads the request body as a binary protobuf and stores it in
+// msg.
+//
+// The request's Content-Type must be "application/vnd.google.protobuf" or one
+// of its common aliases.
+//
+func DecodeProto(req *http.Request, msg proto.Message, opts *Options) error {
+	assert.NotNil(&msg)
+
+	raw, err := readBody(req, protoMediaTypes, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := proto.Unmarshal(raw, msg); err != nil {
+		return MalformedError{err}
+	}
+	return nil
+}
+
+// DecodeProtoText reads the request body as a text protobuf and stores it in
+// msg.
+//
+// The request's Content-Type must be "text/plain".
+//
+func DecodeProtoText(req *http.Request, msg proto.Message, opts *Options) error {
+	assert.NotNil(&msg)
+
+	raw, err := readBody(req, protoTextMediaTypes, opts)
+	if err != nil {
+		return err
+	}
+
+	o := prototext.UnmarshalOptions{DiscardUnknown: !opts.disallowUnknownFields()}
+	if err := o.Unmarshal(raw, msg); err != nil {
+		return MalformedError{err}
+	}
+	return nil
+}
+
+// DecodeForm reads the request body as a URL-encoded HTML form.
+//
+// The request's Content-Type must be "application/x-www-form-urlencoded".
+// Unlike http.Request.ParseForm, the query string is not consulted.
+//
+func DecodeForm(req *http.Request, opts *Options) (url.Values, error) {
+	raw, err := readBody(req, formMediaTypes, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := url.ParseQuery(string(raw))
+	if err != nil {
+		return nil, MalformedError{err}
+	}
+	return values, nil
+}
+
+func checkContentType(req *http.Request, allowed []string) error {
+	str := req.Header.Get("Content-Type")
+	if str == "" {
+		return UnsupportedMediaTypeError{Allowed: allowed}
+	}
+
+	mediaType, _, err := mime.ParseMediaType(str)
+	if err != nil {
+		return UnsupportedMediaTypeError{ContentType: str, Allowed: allowed}
+	}
+
+	for _, pattern := range allowed {
+		if mediaType == pattern {
+			return nil
+		}
+		if i := strings.IndexByte(pattern, '*'); i >= 0 {
+			prefix, suffix := pattern[:i], pattern[i+1:]
+			if len(mediaType) > len(prefix)+len(suffix) && strings.HasPrefix(mediaType, prefix) && strings.HasSuffix(mediaType, suffix) {
+				return nil
+			}
+		}
+	}
+	return UnsupportedMediaTypeError{ContentType: str, Allowed: allowed}
+}
+
+func readBody(req *http.Request, allowed []string, opts *Options) ([]byte, error) {
+	assert.NotNil(&req)
+
+	if err := checkContentType(req, allowed); err != nil {
+		return nil, err
+	}
+
+	max := opts.maxBytes()
+	if max >= 0 && req.ContentLength > max {
+		return nil, TooLargeError{MaxBytes: max}
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	var r io.Reader = limitReader(req.Body, max)
+
+	encoding := strings.ToLower(strings.TrimSpace(req.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+		// pass
+
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, decodeError(err)
+		}
+		defer zr.Close()
+		r = limitReader(zr, max)
+
+	case "deflate":
+		zr, err := zlib.NewReader(r)
+		if err != nil {
+			return nil, decodeError(err)
+		}
+		defer zr.Close()
+		r = limitReader(zr, max)
+
+	default:
+		return nil, UnsupportedEncodingError{ContentEncoding: encoding}
+	}
+
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, decodeError(err)
+	}
+	return raw, nil
+}
+
+func decodeError(err error) error {
+	var tooLarge TooLargeError
+	if errors.As(err, &tooLarge) {
+		return err
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, gzip.ErrHeader) || errors.Is(err, gzip.ErrChecksum) || errors.Is(err, zlib.ErrHeader) || errors.Is(err, zlib.ErrChecksum) {
+		return MalformedError{err}
+	}
+	return err
+}
+
+func limitReader(r io.Reader, max int64) io.Reader {
+	if max < 0 {
+		return r
+	}
+	return &limitedReader{r: r, remaining: max, max: max}
+}
+
+// limitedReader is like io.LimitedReader, except that it reports an error
+// instead of EOF when the limit is exceeded.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	max       int64
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if lr.remaining < 0 {
+		return 0, TooLargeError{MaxBytes: lr.max}
+	}
+
+	// Allow one byte beyond the limit, so that we can tell the difference
+	// between "exactly at the limit" and "exceeds the limit".
+	if int64(len(p)) > lr.remaining+1 {
+		p = p[:lr.remaining+1]
+	}
+
+	n, err := lr.r.Read(p)
+	lr.remaining -= int64(n)
+	if lr.remaining < 0 {
+		return 0, TooLargeError{MaxBytes: lr.max}
+	}
+	return n, err
+}
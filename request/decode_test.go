@@ -0,0 +1,182 @@
+package request
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func newRequestForTest(contentType string, contentEncoding string, data []byte) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+	return req
+}
+
+func gzipForTest(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatalf("gzip Write failed: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip Close failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+type point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+func TestDecodeJSON(t *testing.T) {
+	var p point
+	req := newRequestForTest("application/json; charset=utf-8", "", []byte(`{"x":1,"y":2}`))
+	if err := DecodeJSON(req, &p, nil); err != nil {
+		t.Fatalf("DecodeJSON failed: %v", err)
+	}
+	if expect := (point{1, 2}); p != expect {
+		t.Errorf("expected %+v, got %+v", expect, p)
+	}
+
+	p = point{}
+	req = newRequestForTest("application/problem+json", "gzip", gzipForTest(t, `{"x":3,"y":4}`))
+	if err := DecodeJSON(req, &p, nil); err != nil {
+		t.Fatalf("DecodeJSON with gzip failed: %v", err)
+	}
+	if expect := (point{3, 4}); p != expect {
+		t.Errorf("expected %+v, got %+v", expect, p)
+	}
+
+	msg := &wrapperspb.StringValue{}
+	req = newRequestForTest("application/json", "", []byte(`"abc"`))
+	if err := DecodeJSON(req, msg, nil); err != nil {
+		t.Fatalf("DecodeJSON with proto.Message failed: %v", err)
+	}
+	if expect := "abc"; msg.Value != expect {
+		t.Errorf("expected %q, got %q", expect, msg.Value)
+	}
+}
+
+func TestDecodeJSON_Errors(t *testing.T) {
+	type testRow struct {
+		Name            string
+		ContentType     string
+		ContentEncoding string
+		Data            []byte
+		Options         *Options
+		Status          int
+	}
+
+	testData := []testRow{
+		{"NoContentType", "", "", []byte(`{}`), nil, http.StatusUnsupportedMediaType},
+		{"WrongContentType", "text/plain", "", []byte(`{}`), nil, http.StatusUnsupportedMediaType},
+		{"UnknownEncoding", "application/json", "br", []byte(`{}`), nil, http.StatusUnsupportedMediaType},
+		{"Malformed", "application/json", "", []byte(`{"x":`), nil, http.StatusBadRequest},
+		{"Trailing", "application/json", "", []byte(`{} {}`), nil, http.StatusBadRequest},
+		{"Empty", "application/json", "", nil, nil, http.StatusBadRequest},
+		{"UnknownField", "application/json", "", []byte(`{"z":1}`), &Options{DisallowUnknownFields: true}, http.StatusBadRequest},
+		{"TooLarge", "application/json", "", []byte(`{"x":12345}`), &Options{MaxBytes: 8}, http.StatusRequestEntityTooLarge},
+		{"TooLargeGzip", "application/json", "gzip", gzipForTest(t, `{"x":1`+strings.Repeat(" ", 100)+`}`), &Options{MaxBytes: 64}, http.StatusRequestEntityTooLarge},
+		{"BadGzip", "application/json", "gzip", []byte(`{}`), nil, http.StatusBadRequest},
+	}
+
+	for _, row := range testData {
+		var p point
+		req := newRequestForTest(row.ContentType, row.ContentEncoding, row.Data)
+		err := DecodeJSON(req, &p, row.Options)
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", row.Name)
+			continue
+		}
+		if actual := StatusCode(err); actual != row.Status {
+			t.Errorf("%s: expected status %d, got %d (%v)", row.Name, row.Status, actual, err)
+		}
+	}
+
+	var p point
+	req := newRequestForTest("application/json", "", []byte(`{"x":1}`))
+	req.ContentLength = -1
+	err := DecodeJSON(req, &p, &Options{MaxBytes: 7})
+	if err != nil {
+		t.Errorf("DecodeJSON at exactly MaxBytes failed: %v", err)
+	}
+
+	var tooLarge TooLargeError
+	req = newRequestForTest("application/json", "", []byte(`{"x":12}`))
+	req.ContentLength = -1
+	err = DecodeJSON(req, &p, &Options{MaxBytes: 7})
+	if !errors.As(err, &tooLarge) {
+		t.Errorf("expected TooLargeError, got %v", err)
+	}
+}
+
+func TestDecodeProto(t *testing.T) {
+	raw, err := proto.Marshal(wrapperspb.String("abc"))
+	if err != nil {
+		t.Fatalf("proto.Marshal failed: %v", err)
+	}
+
+	msg := &wrapperspb.StringValue{}
+	req := newRequestForTest("application/vnd.google.protobuf", "", raw)
+	if err := DecodeProto(req, msg, nil); err != nil {
+		t.Fatalf("DecodeProto failed: %v", err)
+	}
+	if expect := "abc"; msg.Value != expect {
+		t.Errorf("expected %q, got %q", expect, msg.Value)
+	}
+
+	req = newRequestForTest("application/json", "", raw)
+	var unsupported UnsupportedMediaTypeError
+	if err := DecodeProto(req, msg, nil); !errors.As(err, &unsupported) {
+		t.Errorf("expected UnsupportedMediaTypeError, got %v", err)
+	}
+}
+
+func TestDecodeProtoText(t *testing.T) {
+	msg := &wrapperspb.StringValue{}
+	req := newRequestForTest("text/plain; charset=utf-8", "", []byte(`value: "abc"`))
+	if err := DecodeProtoText(req, msg, nil); err != nil {
+		t.Fatalf("DecodeProtoText failed: %v", err)
+	}
+	if expect := "abc"; msg.Value != expect {
+		t.Errorf("expected %q, got %q", expect, msg.Value)
+	}
+
+	req = newRequestForTest("text/plain", "", []byte(`bogus: 1`))
+	if err := DecodeProtoText(req, msg, &Options{DisallowUnknownFields: true}); StatusCode(err) != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %v", err)
+	}
+}
+
+func TestDecodeForm(t *testing.T) {
+	req := newRequestForTest("application/x-www-form-urlencoded", "", []byte(`a=1&b=2&a=3`))
+	values, err := DecodeForm(req, nil)
+	if err != nil {
+		t.Fatalf("DecodeForm failed: %v", err)
+	}
+	if expect, actual := []string{"1", "3"}, values["a"]; len(actual) != 2 || actual[0] != expect[0] || actual[1] != expect[1] {
+		t.Errorf("expected a=%q, got a=%q", expect, actual)
+	}
+	if expect, actual := "2", values.Get("b"); expect != actual {
+		t.Errorf("expected b=%q, got b=%q", expect, actual)
+	}
+
+	req = newRequestForTest("application/x-www-form-urlencoded", "", []byte(`a=%zz`))
+	if _, err := DecodeForm(req, nil); StatusCode(err) != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %v", err)
+	}
+}
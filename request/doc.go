@@ -0,0 +1,2 @@
+// Package request provides helpers for decoding HTTP request bodies.
+package request
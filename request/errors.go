@@ -0,0 +1,118 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// StatusCoder is implemented by errors which map to a specific HTTP status
+// code.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// StatusCode returns the HTTP status code which best describes the given
+// error returned by one of the Decode functions, for use with
+// response.Builder.ErrorPage.  Errors which do not provide a StatusCode method
+// are mapped to "400 Bad Request".
+func StatusCode(err error) int {
+	var x StatusCoder
+	if errors.As(err, &x) {
+		return x.StatusCode()
+	}
+	return http.StatusBadRequest
+}
+
+type UnsupportedMediaTypeError struct {
+	ContentType string
+	Allowed     []string
+}
+
+func (err UnsupportedMediaTypeError) GoString() string {
+	return fmt.Sprintf("UnsupportedMediaTypeError{%q, %#v}", err.ContentType, err.Allowed)
+}
+
+func (err UnsupportedMediaTypeError) Error() string {
+	if err.ContentType == "" {
+		return fmt.Sprintf("missing Content-Type; expected one of [%s]", strings.Join(err.Allowed, ", "))
+	}
+	return fmt.Sprintf("unsupported Content-Type %q; expected one of [%s]", err.ContentType, strings.Join(err.Allowed, ", "))
+}
+
+func (err UnsupportedMediaTypeError) StatusCode() int {
+	return http.StatusUnsupportedMediaType
+}
+
+var (
+	_ error       = UnsupportedMediaTypeError{}
+	_ StatusCoder = UnsupportedMediaTypeError{}
+)
+
+type UnsupportedEncodingError struct {
+	ContentEncoding string
+}
+
+func (err UnsupportedEncodingError) GoString() string {
+	return fmt.Sprintf("UnsupportedEncodingError{%q}", err.ContentEncoding)
+}
+
+func (err UnsupportedEncodingError) Error() string {
+	return fmt.Sprintf("unsupported Content-Encoding %q", err.ContentEncoding)
+}
+
+func (err UnsupportedEncodingError) StatusCode() int {
+	return http.StatusUnsupportedMediaType
+}
+
+var (
+	_ error       = UnsupportedEncodingError{}
+	_ StatusCoder = UnsupportedEncodingError{}
+)
+
+type TooLargeError struct {
+	MaxBytes int64
+}
+
+func (err TooLargeError) GoString() string {
+	return fmt.Sprintf("TooLargeError{%d}", err.MaxBytes)
+}
+
+func (err TooLargeError) Error() string {
+	return fmt.Sprintf("request body is larger than the limit of %d bytes", err.MaxBytes)
+}
+
+func (err TooLargeError) StatusCode() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+var (
+	_ error       = TooLargeError{}
+	_ StatusCoder = TooLargeError{}
+)
+
+type MalformedError struct {
+	Err error
+}
+
+func (err MalformedError) GoString() string {
+	return fmt.Sprintf("MalformedError{%#v}", err.Err)
+}
+
+func (err MalformedError) Error() string {
+	return fmt.Sprintf("malformed request body: %v", err.Err)
+}
+
+func (err MalformedError) Unwrap() error {
+	return err.Err
+}
+
+func (err MalformedError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+var (
+	_ error       = MalformedError{}
+	_ StatusCoder = MalformedError{}
+)
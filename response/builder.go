@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/chronos-tachyon/assert"
 	"google.golang.org/protobuf/encoding/prototext"
@@ -20,11 +21,13 @@ func NewBuilder() *Builder {
 }
 
 type Builder struct {
-	gen  PageGenerator
-	code int
-	hdrs http.Header
-	body body.Body
-	err  error
+	gen      PageGenerator
+	reqID    string
+	code     int
+	hdrs     http.Header
+	body     body.Body
+	variants map[string]body.Body
+	err      error
 }
 
 // PageGenerator returns the associated PageGenerator instance, or
@@ -77,6 +80,14 @@ func (builder *Builder) WithPageGenerator(gen PageGenerator) *Builder {
 	return builder
 }
 
+// WithRequestID associates the given request ID with this Builder.  This
+// affects future calls to ErrorPage, if the PageGenerator implements
+// RequestIDPageGenerator.
+func (builder *Builder) WithRequestID(id string) *Builder {
+	builder.reqID = id
+	return builder
+}
+
 // WithStatus associates the given HTTP status code with this Builder.
 //
 // The given value MUST lie between 200 and 999 inclusive.
@@ -163,6 +174,14 @@ func (builder *Builder) WithETag(tag string, isStrong bool) *Builder {
 	return builder
 }
 
+// WithLastModified sets the Last-Modified header.
+func (builder *Builder) WithLastModified(t time.Time) *Builder {
+	assert.Assert(!t.IsZero(), "time must not be zero")
+	hdrs := builder.Headers()
+	hdrs.Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+	return builder
+}
+
 // WithDigest adds the given Digest header.
 func (builder *Builder) WithDigest(algo string, sum []byte) *Builder {
 	assert.Assert(algo != "", "algo must not be empty")
@@ -183,6 +202,33 @@ func (builder *Builder) WithBody(b body.Body) *Builder {
 	return builder
 }
 
+// WithEncodedVariants associates alternative Bodies with this Builder, taking
+// ownership of them.  Each Body holds the same content as the main Body, but
+// encoded with the content coding given by its key, e.g. "gzip" or "br".  Any
+// previously associated variants are closed and replaced.
+//
+// Response.ServeRequest chooses between the main Body and its variants based
+// on the request's Accept-Encoding header.
+//
+// The keys MUST NOT be empty or "identity", and the values MUST NOT be nil.
+//
+func (builder *Builder) WithEncodedVariants(variants map[string]body.Body) *Builder {
+	var m map[string]body.Body
+	if len(variants) != 0 {
+		m = make(map[string]body.Body, len(variants))
+	}
+	for name, b := range variants {
+		name = strings.ToLower(strings.TrimSpace(name))
+		assert.Assert(name != "", "content coding must not be empty")
+		assert.Assert(name != identityEncoding, "content coding must not be identity")
+		assert.NotNil(&b)
+		m[name] = b
+	}
+	closeVariants(builder.variants)
+	builder.variants = m
+	return builder
+}
+
 // WithJSON converts the given value to a JSON Body, then associates it with
 // this Builder.
 //
@@ -254,6 +300,8 @@ func (builder *Builder) RedirectPage(code int, location string) *Builder {
 	builder.code = code
 	builder.hdrs = h
 	builder.body = b
+	closeVariants(builder.variants)
+	builder.variants = nil
 	builder.err = nil
 	return builder
 }
@@ -265,11 +313,13 @@ func (builder *Builder) ErrorPage(code int, err error) *Builder {
 	assert.NotNil(&err)
 
 	gen := builder.PageGenerator()
-	h, b := gen.GenerateErrorPage(code, err)
+	h, b := generateErrorPage(gen, code, err, builder.reqID)
 
 	builder.code = code
 	builder.hdrs = h
 	builder.body = b
+	closeVariants(builder.variants)
+	builder.variants = nil
 	builder.err = err
 	return builder
 }
@@ -281,14 +331,24 @@ func (builder *Builder) Copy() (*Builder, error) {
 		return nil, err
 	}
 
+	variants2, err := copyVariants(builder.variants)
+	if err != nil {
+		if body2 != nil {
+			_ = body2.Close()
+		}
+		return nil, err
+	}
+
 	hdrs2 := copyHeaders(builder.hdrs)
 
 	out := &Builder{
-		gen:  builder.gen,
-		code: builder.code,
-		hdrs: hdrs2,
-		body: body2,
-		err:  builder.err,
+		gen:      builder.gen,
+		reqID:    builder.reqID,
+		code:     builder.code,
+		hdrs:     hdrs2,
+		body:     body2,
+		variants: variants2,
+		err:      builder.err,
 	}
 	return out, nil
 }
@@ -305,8 +365,11 @@ func (builder *Builder) Copy() (*Builder, error) {
 // non-negative BytesRemaining(), then the Content-Length header is
 // automatically populated from BytesRemaining().
 //
+// Any encoded variants are transferred to the Response as well.
+//
 // After calling this method, the Builder is reset to an empty state and is
-// ready to build another Response.  Only the PageGenerator is retained.
+// ready to build another Response.  Only the PageGenerator and request ID are
+// retained.
 //
 func (builder *Builder) Build() *Response {
 	assert.Assert(builder.body != nil, "must specify body")
@@ -314,11 +377,13 @@ func (builder *Builder) Build() *Response {
 	code := builder.code
 	hdrs := builder.hdrs
 	body := builder.body
+	variants := builder.variants
 	err := builder.err
 
 	builder.code = 0
 	builder.hdrs = nil
 	builder.body = nil
+	builder.variants = nil
 	builder.err = nil
 
 	if code == 0 {
@@ -347,9 +412,11 @@ func (builder *Builder) Build() *Response {
 	}
 
 	return &Response{
-		code: code,
-		hdrs: hdrs,
-		body: body,
-		err:  err,
+		gen:      builder.gen,
+		code:     code,
+		hdrs:     hdrs,
+		body:     body,
+		variants: variants,
+		err:      err,
 	}
 }
@@ -0,0 +1,99 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chronos-tachyon/morehttp/body"
+)
+
+// ErrPreconditionFailed is the error which is passed to the PageGenerator
+// when a request's If-Match or If-Unmodified-Since header does not match.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+func joinedHeader(h http.Header, name string) string {
+	return strings.Join(h.Values(name), ",")
+}
+
+// evaluatePreconditions implements the precedence rules of RFC 7232 section
+// 6, returning http.StatusNotModified, http.StatusPreconditionFailed, or 0 if
+// the request should proceed normally.
+func evaluatePreconditions(req *http.Request, hdrs http.Header) int {
+	etag, hasETag := parseETag(hdrs.Get("Etag"))
+	lastModified, err := http.ParseTime(hdrs.Get("Last-Modified"))
+	hasLastModified := (err == nil)
+	isGetOrHead := (req.Method == http.MethodGet || req.Method == http.MethodHead)
+
+	if ifMatch := joinedHeader(req.Header, "If-Match"); ifMatch != "" {
+		list, isStar := parseETagList(ifMatch)
+		if !isStar && !(hasETag && anyETagMatches(list, etag, entityTag.strongMatch)) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ifUnmodifiedSince := req.Header.Get("If-Unmodified-Since"); ifUnmodifiedSince != "" && hasLastModified {
+		t, err := http.ParseTime(ifUnmodifiedSince)
+		if err == nil && isModifiedSince(lastModified, t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if ifNoneMatch := joinedHeader(req.Header, "If-None-Match"); ifNoneMatch != "" {
+		list, isStar := parseETagList(ifNoneMatch)
+		if isStar || (hasETag && anyETagMatches(list, etag, entityTag.weakMatch)) {
+			if isGetOrHead {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if ifModifiedSince := req.Header.Get("If-Modified-Since"); ifModifiedSince != "" && hasLastModified && isGetOrHead {
+		t, err := http.ParseTime(ifModifiedSince)
+		if err == nil && !isModifiedSince(lastModified, t) {
+			return http.StatusNotModified
+		}
+	}
+
+	return 0
+}
+
+func anyETagMatches(list []entityTag, etag entityTag, match func(entityTag, entityTag) bool) bool {
+	for _, item := range list {
+		if match(item, etag) {
+			return true
+		}
+	}
+	return false
+}
+
+func isModifiedSince(lastModified time.Time, t time.Time) bool {
+	// HTTP dates have a resolution of one second.
+	return lastModified.Truncate(time.Second).After(t)
+}
+
+func (resp *Response) applyPreconditions(req *http.Request, code int, hdrs http.Header, b body.Body) (int, http.Header, body.Body) {
+	switch evaluatePreconditions(req, hdrs) {
+	case http.StatusNotModified:
+		_ = b.Close()
+
+		hdrs = copyHeaders(hdrs)
+		if hdrs == nil {
+			hdrs = make(http.Header, 16)
+		}
+		hdrs.Del("Content-Type")
+		hdrs.Del("Content-Length")
+		hdrs.Del("Content-Encoding")
+		if _, found := hdrs[http.CanonicalHeaderKey("Etag")]; found {
+			hdrs.Del("Last-Modified")
+		}
+		return http.StatusNotModified, hdrs, body.Empty()
+
+	case http.StatusPreconditionFailed:
+		_ = b.Close()
+
+		h, eb := generateErrorPage(resp.PageGenerator(), http.StatusPreconditionFailed, ErrPreconditionFailed, RequestIDFromContext(req.Context()))
+		return http.StatusPreconditionFailed, h, eb
+
+	default:
+		return code, hdrs, b
+	}
+}
@@ -0,0 +1,158 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chronos-tachyon/morehttp/body"
+	"github.com/chronos-tachyon/morehttp/internal/mockreader"
+)
+
+func TestParseETagList(t *testing.T) {
+	list, isStar := parseETagList(`"a", W/"b" ,"c"`)
+	if isStar {
+		t.Errorf("expected isStar=false")
+	}
+	expect := []entityTag{{`"a"`, false}, {`"b"`, true}, {`"c"`, false}}
+	if len(list) != len(expect) {
+		t.Fatalf("expected %v, got %v", expect, list)
+	}
+	for index := range expect {
+		if list[index] != expect[index] {
+			t.Errorf("index %d: expected %v, got %v", index, expect[index], list[index])
+		}
+	}
+
+	if _, isStar := parseETagList(" * "); !isStar {
+		t.Errorf("expected isStar=true")
+	}
+}
+
+func TestServeRequest_Preconditions(t *testing.T) {
+	modTime := time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)
+	before := modTime.Add(-time.Hour).Format(http.TimeFormat)
+	exact := modTime.Format(http.TimeFormat)
+	after := modTime.Add(time.Hour).Format(http.TimeFormat)
+
+	type testRow struct {
+		Name    string
+		Method  string
+		Headers map[string]string
+		Status  int
+	}
+
+	testData := []testRow{
+		{"None", http.MethodGet, nil, http.StatusOK},
+		{"IfMatch-Hit", http.MethodPut, map[string]string{"If-Match": `"x", "v1"`}, http.StatusOK},
+		{"IfMatch-Star", http.MethodPut, map[string]string{"If-Match": `*`}, http.StatusOK},
+		{"IfMatch-Miss", http.MethodPut, map[string]string{"If-Match": `"x"`}, http.StatusPreconditionFailed},
+		{"IfMatch-Weak", http.MethodPut, map[string]string{"If-Match": `W/"v1"`}, http.StatusPreconditionFailed},
+		{"IfUnmodifiedSince-Before", http.MethodPut, map[string]string{"If-Unmodified-Since": before}, http.StatusPreconditionFailed},
+		{"IfUnmodifiedSince-Exact", http.MethodPut, map[string]string{"If-Unmodified-Since": exact}, http.StatusOK},
+		{"IfUnmodifiedSince-IgnoredByIfMatch", http.MethodPut, map[string]string{"If-Match": `"v1"`, "If-Unmodified-Since": before}, http.StatusOK},
+		{"IfNoneMatch-Hit", http.MethodGet, map[string]string{"If-None-Match": `W/"v1"`}, http.StatusNotModified},
+		{"IfNoneMatch-HitHead", http.MethodHead, map[string]string{"If-None-Match": `"v1"`}, http.StatusNotModified},
+		{"IfNoneMatch-HitPost", http.MethodPost, map[string]string{"If-None-Match": `"v1"`}, http.StatusPreconditionFailed},
+		{"IfNoneMatch-Star", http.MethodGet, map[string]string{"If-None-Match": `*`}, http.StatusNotModified},
+		{"IfNoneMatch-Miss", http.MethodGet, map[string]string{"If-None-Match": `"x"`}, http.StatusOK},
+		{"IfModifiedSince-Before", http.MethodGet, map[string]string{"If-Modified-Since": before}, http.StatusOK},
+		{"IfModifiedSince-Exact", http.MethodGet, map[string]string{"If-Modified-Since": exact}, http.StatusNotModified},
+		{"IfModifiedSince-After", http.MethodGet, map[string]string{"If-Modified-Since": after}, http.StatusNotModified},
+		{"IfModifiedSince-Post", http.MethodPost, map[string]string{"If-Modified-Since": after}, http.StatusOK},
+		{"IfModifiedSince-IgnoredByIfNoneMatch", http.MethodGet, map[string]string{"If-None-Match": `"x"`, "If-Modified-Since": after}, http.StatusOK},
+		{"IfModifiedSince-Invalid", http.MethodGet, map[string]string{"If-Modified-Since": "yesterday"}, http.StatusOK},
+	}
+
+	hdrs := NewBuilder().WithETag("v1", true).WithLastModified(modTime).Headers()
+
+	for _, row := range testData {
+		req := httptest.NewRequest(row.Method, "/", nil)
+		for k, v := range row.Headers {
+			req.Header.Set(k, v)
+		}
+
+		expect := row.Status
+		if expect == http.StatusOK {
+			expect = 0
+		}
+		if actual := evaluatePreconditions(req, hdrs); expect != actual {
+			t.Errorf("%s: expected %d, got %d", row.Name, expect, actual)
+		}
+	}
+}
+
+func TestServeRequest_NotModified(t *testing.T) {
+	r := mockreader.New(mockreader.ExpectClose(nil))
+	b, err := body.FromReader(mockreader.Wrapper000{Inner: r})
+	if err != nil {
+		t.Fatalf("FromReader failed: %v", err)
+	}
+
+	resp := NewBuilder().
+		WithContentType("text/plain").
+		WithETag("v1", true).
+		WithBody(b).
+		Build()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"v1"`)
+
+	result := serveForTest(t, resp, req)
+	if expect := http.StatusNotModified; result.StatusCode != expect {
+		t.Errorf("expected status %d, got %d", expect, result.StatusCode)
+	}
+	if v := result.Header.Get("Content-Type"); v != "" {
+		t.Errorf("expected no Content-Type, got %q", v)
+	}
+	if expect, actual := `"v1"`, result.Header.Get("Etag"); expect != actual {
+		t.Errorf("expected ETag %q, got %q", expect, actual)
+	}
+	if actual := readAllForTest(t, result.Body); actual != "" {
+		t.Errorf("expected empty body, got %q", actual)
+	}
+}
+
+func TestServeRequest_PreconditionFailed(t *testing.T) {
+	r := mockreader.New(mockreader.ExpectClose(nil))
+	b, err := body.FromReader(mockreader.Wrapper000{Inner: r})
+	if err != nil {
+		t.Fatalf("FromReader failed: %v", err)
+	}
+
+	resp := NewBuilder().
+		WithETag("v1", true).
+		WithBody(b).
+		Build()
+
+	req := httptest.NewRequest(http.MethodPut, "/", nil)
+	req.Header.Set("If-Match", `"v2"`)
+
+	result := serveForTest(t, resp, req)
+	if expect := http.StatusPreconditionFailed; result.StatusCode != expect {
+		t.Errorf("expected status %d, got %d", expect, result.StatusCode)
+	}
+	if expect, actual := "412 Precondition Failed\r\n", readAllForTest(t, result.Body); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+}
+
+func TestServeRequest_PreconditionFailedWithRequestID(t *testing.T) {
+	resp := NewBuilder().
+		WithETag("v1", true).
+		WithBody(body.FromString("content")).
+		Build()
+
+	req := httptest.NewRequest(http.MethodPut, "/", nil)
+	req = req.WithContext(ContextWithRequestID(req.Context(), "req-412"))
+	req.Header.Set("If-Match", `"v2"`)
+
+	result := serveForTest(t, resp, req)
+	if expect := http.StatusPreconditionFailed; result.StatusCode != expect {
+		t.Errorf("expected status %d, got %d", expect, result.StatusCode)
+	}
+	if expect, actual := "412 Precondition Failed\r\nRequest ID: req-412\r\n", readAllForTest(t, result.Body); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+}
@@ -0,0 +1,83 @@
+package response
+
+import (
+	"strings"
+)
+
+type entityTag struct {
+	opaque string
+	weak   bool
+}
+
+func (tag entityTag) strongMatch(other entityTag) bool {
+	return !tag.weak && !other.weak && tag.opaque == other.opaque
+}
+
+func (tag entityTag) weakMatch(other entityTag) bool {
+	return tag.opaque == other.opaque
+}
+
+// scanETag parses the entity-tag at the start of str, returning the tag and
+// the unparsed remainder of str.
+func scanETag(str string) (entityTag, string, bool) {
+	var tag entityTag
+
+	str = strings.TrimLeft(str, " \t")
+	if strings.HasPrefix(str, "W/") {
+		tag.weak = true
+		str = str[2:]
+	}
+
+	if len(str) < 2 || str[0] != '"' {
+		return entityTag{}, "", false
+	}
+
+	for i := 1; i < len(str); i++ {
+		ch := str[i]
+		switch {
+		case ch == '"':
+			tag.opaque = str[:i+1]
+			return tag, str[i+1:], true
+		case ch == 0x21 || (ch >= 0x23 && ch != 0x7f):
+			// pass
+		default:
+			return entityTag{}, "", false
+		}
+	}
+
+	return entityTag{}, "", false
+}
+
+// parseETag parses a single entity-tag, such as the value of an ETag header.
+func parseETag(str string) (entityTag, bool) {
+	tag, rest, ok := scanETag(str)
+	if !ok || strings.TrimSpace(rest) != "" {
+		return entityTag{}, false
+	}
+	return tag, true
+}
+
+// parseETagList parses the value of an If-Match or If-None-Match header.
+// The boolean result is true iff the header value is "*".
+func parseETagList(str string) ([]entityTag, bool) {
+	str = strings.TrimSpace(str)
+	if str == "*" {
+		return nil, true
+	}
+
+	var list []entityTag
+	for {
+		str = strings.TrimLeft(str, " \t,")
+		if str == "" {
+			return list, false
+		}
+
+		tag, rest, ok := scanETag(str)
+		if !ok {
+			return list, false
+		}
+
+		list = append(list, tag)
+		str = rest
+	}
+}
@@ -0,0 +1,308 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"io/fs"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chronos-tachyon/assert"
+
+	"github.com/chronos-tachyon/morehttp/body"
+)
+
+// DefaultHeartbeatInterval is the default value of
+// EventStreamOptions.Heartbeat.
+const DefaultHeartbeatInterval = 15 * time.Second
+
+// ErrEventStreamClosed is returned by EventStream.Send once the stream has
+// been closed, either by the producer or because the response has finished.
+var ErrEventStreamClosed = errors.New("event stream is closed")
+
+// ErrInvalidEvent is returned by EventStream.Send when an Event cannot be
+// represented in the text/event-stream format.
+var ErrInvalidEvent = errors.New("invalid event")
+
+// Event is a single Server-Sent Event.
+type Event struct {
+	// ID, if non-empty, sets the client's last event ID.  It MUST NOT
+	// contain CR, LF, or NUL.
+	ID string
+
+	// Event, if non-empty, is the event type.  It MUST NOT contain CR or
+	// LF.
+	Event string
+
+	// Data is the event payload.  It may span multiple lines.  An Event
+	// with an ID, Event, or Data is always sent with at least one data
+	// line, even if Data is empty, since clients discard events which have
+	// none.
+	Data string
+
+	// Retry, if positive, sets the client's reconnection delay.
+	Retry time.Duration
+
+	// Comment, if non-empty, is sent as a comment.  Clients ignore it.
+	Comment string
+}
+
+func (ev Event) validate() error {
+	if strings.ContainsAny(ev.ID, "\r\n\x00") {
+		return fmt.Errorf("%w: ID %q contains CR, LF, or NUL", ErrInvalidEvent, ev.ID)
+	}
+	if strings.ContainsAny(ev.Event, "\r\n") {
+		return fmt.Errorf("%w: event type %q contains CR or LF", ErrInvalidEvent, ev.Event)
+	}
+	return nil
+}
+
+func (ev Event) appendTo(buf []byte) []byte {
+	if ev.Comment != "" {
+		for _, line := range splitEventLines(ev.Comment) {
+			buf = appendEventField(buf, "", line)
+		}
+	}
+	if ev.Event != "" {
+		buf = appendEventField(buf, "event", ev.Event)
+	}
+	if ev.ID != "" {
+		buf = appendEventField(buf, "id", ev.ID)
+	}
+	if ev.Retry > 0 {
+		buf = appendEventField(buf, "retry", strconv.FormatInt(int64(ev.Retry/time.Millisecond), 10))
+	}
+	if ev.ID != "" || ev.Event != "" || ev.Data != "" {
+		for _, line := range splitEventLines(ev.Data) {
+			buf = appendEventField(buf, "data", line)
+		}
+	}
+	return append(buf, '\n')
+}
+
+func appendEventField(buf []byte, name string, value string) []byte {
+	buf = append(buf, name...)
+	buf = append(buf, ':', ' ')
+	buf = append(buf, value...)
+	return append(buf, '\n')
+}
+
+func splitEventLines(str string) []string {
+	str = strings.ReplaceAll(str, "\r\n", "\n")
+	str = strings.ReplaceAll(str, "\r", "\n")
+	return strings.Split(str, "\n")
+}
+
+// EventStreamOptions holds options for NewEventStream.
+type EventStreamOptions struct {
+	// Heartbeat is the interval between comment lines sent to keep idle
+	// connections open.  If zero, DefaultHeartbeatInterval is used; if
+	// negative, no heartbeats are sent.
+	Heartbeat time.Duration
+
+	// Retry, if positive, is sent to the client at the start of the stream
+	// as its reconnection delay.
+	Retry time.Duration
+}
+
+// EventStream is a live stream of Server-Sent Events.
+//
+// The producer calls Send for each Event, then Close when done.  Meanwhile,
+// the Response built by Builder.WithEventStream writes each Event to the
+// client, flushing after each one, until the producer calls Close, the
+// request context is cancelled, or the client goes away.
+//
+// Send blocks until the Event has been handed to the Response, so a producer
+// cannot get ahead of a slow client.
+//
+type EventStream struct {
+	ctx         context.Context
+	lastEventID string
+	heartbeat   time.Duration
+	retry       time.Duration
+	events      chan []byte
+	closed      chan struct{}
+	stopped     chan struct{}
+	closeOnce   sync.Once
+	stopOnce    sync.Once
+	bodies      int32
+	body        body.Body
+}
+
+// NewEventStream constructs a new EventStream in reply to the given request.
+//
+// The options argument MAY be nil, in which case defaults are used.
+//
+func NewEventStream(req *http.Request, opts *EventStreamOptions) *EventStream {
+	assert.NotNil(&req)
+
+	if opts == nil {
+		opts = &EventStreamOptions{}
+	}
+
+	heartbeat := opts.Heartbeat
+	if heartbeat == 0 {
+		heartbeat = DefaultHeartbeatInterval
+	}
+
+	s := &EventStream{
+		ctx:         req.Context(),
+		lastEventID: strings.TrimSpace(req.Header.Get("Last-Event-ID")),
+		heartbeat:   heartbeat,
+		retry:       opts.Retry,
+		events:      make(chan []byte),
+		closed:      make(chan struct{}),
+		stopped:     make(chan struct{}),
+		bodies:      1,
+	}
+	s.body = &eventStreamBody{Body: body.FromFunc(s.run), stream: s}
+	return s
+}
+
+// LastEventID returns the value of the request's Last-Event-ID header, which
+// a reconnecting client uses to report the ID of the last Event it received.
+// Returns the empty string for new clients.
+func (s *EventStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Done returns a channel which is closed once the Response has stopped
+// consuming Events.
+func (s *EventStream) Done() <-chan struct{} {
+	return s.stopped
+}
+
+// Send queues an Event for delivery, blocking until the Response accepts it.
+//
+// Returns ErrEventStreamClosed if the stream has stopped, or the context's
+// error if the request context has been cancelled.
+//
+func (s *EventStream) Send(ev Event) error {
+	if err := ev.validate(); err != nil {
+		return err
+	}
+
+	select {
+	case <-s.closed:
+		return ErrEventStreamClosed
+	default:
+	}
+
+	buf := ev.appendTo(make([]byte, 0, 64+len(ev.Data)))
+	select {
+	case s.events <- buf:
+		return nil
+	case <-s.closed:
+		return ErrEventStreamClosed
+	case <-s.stopped:
+		return ErrEventStreamClosed
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// Close ends the stream.  The Response finishes once it has written all Events
+// accepted by Send.  It is safe to call Close more than once.
+func (s *EventStream) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+	return nil
+}
+
+func (s *EventStream) stop() {
+	s.stopOnce.Do(func() { close(s.stopped) })
+}
+
+// WithEventStream associates the given EventStream with this Builder.
+//
+// This method also adds the headers "Content-Type: text/event-stream" and
+// "Cache-Control: no-cache".
+//
+func (builder *Builder) WithEventStream(s *EventStream) *Builder {
+	assert.NotNil(&s)
+	builder.body = s.body
+	hdrs := builder.Headers()
+	hdrs.Set("Content-Type", "text/event-stream")
+	hdrs.Set("Cache-Control", "no-cache")
+	return builder
+}
+
+// run writes Events to w as they arrive, flushing after each one, until the
+// producer calls Close, the request context is cancelled, or the Body is
+// closed.
+func (s *EventStream) run(w *body.PipeWriter) error {
+	defer s.stop()
+
+	var tick <-chan time.Time
+	if s.heartbeat > 0 {
+		ticker := time.NewTicker(s.heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var buf []byte
+	if s.retry > 0 {
+		buf = Event{Retry: s.retry}.appendTo(nil)
+	}
+
+	for {
+		if buf != nil {
+			if _, err := w.Write(buf); err != nil {
+				return err
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case buf = <-s.events:
+		case <-tick:
+			buf = []byte(":\n\n")
+		case <-s.closed:
+			return nil
+		case <-s.ctx.Done():
+			return nil
+		case <-w.Done():
+			return nil
+		}
+	}
+}
+
+// eventStreamBody ensures that EventStream.Done is closed when the Response and
+// all of its copies are closed, even if the stream was never served.
+type eventStreamBody struct {
+	body.Body
+	stream *EventStream
+}
+
+func (b *eventStreamBody) WriteTo(w io.Writer) (int64, error) {
+	return b.Body.(io.WriterTo).WriteTo(w)
+}
+
+func (b *eventStreamBody) Close() error {
+	err := b.Body.Close()
+	if !errors.Is(err, fs.ErrClosed) && atomic.AddInt32(&b.stream.bodies, -1) == 0 {
+		b.stream.stop()
+	}
+	return err
+}
+
+func (b *eventStreamBody) Copy() (body.Body, error) {
+	dupe, err := b.Body.Copy()
+	if err != nil || dupe == body.AlreadyClosed() {
+		return dupe, err
+	}
+	atomic.AddInt32(&b.stream.bodies, 1)
+	return &eventStreamBody{Body: dupe, stream: b.stream}, nil
+}
+
+var (
+	_ body.Body   = (*eventStreamBody)(nil)
+	_ io.WriterTo = (*eventStreamBody)(nil)
+)
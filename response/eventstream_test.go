@@ -0,0 +1,171 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvent_Framing(t *testing.T) {
+	type testRow struct {
+		Event  Event
+		Expect string
+	}
+
+	testData := []testRow{
+		{Event{Data: "hello"}, "data: hello\n\n"},
+		{Event{Data: "a\nb\r\nc\rd"}, "data: a\ndata: b\ndata: c\ndata: d\n\n"},
+		{Event{ID: "7", Event: "update", Data: "x"}, "event: update\nid: 7\ndata: x\n\n"},
+		{Event{Retry: 2500 * time.Millisecond}, "retry: 2500\n\n"},
+		{Event{Comment: "one\ntwo"}, ": one\n: two\n\n"},
+		{Event{Event: "ping"}, "event: ping\ndata: \n\n"},
+		{Event{ID: "8"}, "id: 8\ndata: \n\n"},
+		{Event{Comment: "note", Event: "ping"}, ": note\nevent: ping\ndata: \n\n"},
+	}
+
+	for _, row := range testData {
+		if actual := string(row.Event.appendTo(nil)); actual != row.Expect {
+			t.Errorf("%#v: expected %q, got %q", row.Event, row.Expect, actual)
+		}
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Last-Event-ID", "41")
+
+	stream := NewEventStream(req, &EventStreamOptions{Retry: time.Second})
+	if expect, actual := "41", stream.LastEventID(); expect != actual {
+		t.Errorf("LastEventID: expected %q, got %q", expect, actual)
+	}
+
+	if err := stream.Send(Event{ID: "bad\nid"}); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("Send: expected ErrInvalidEvent, got %v", err)
+	}
+
+	go func() {
+		defer stream.Close()
+		_ = stream.Send(Event{ID: "42", Data: "first"})
+		_ = stream.Send(Event{ID: "43", Event: "update", Data: "second"})
+	}()
+
+	resp := NewBuilder().WithEventStream(stream).Build()
+
+	w := httptest.NewRecorder()
+	if err := resp.ServeRequest(w, req); err != nil {
+		t.Fatalf("ServeRequest failed: %v", err)
+	}
+
+	if expect, actual := "text/event-stream", w.Header().Get("Content-Type"); expect != actual {
+		t.Errorf("expected Content-Type %q, got %q", expect, actual)
+	}
+	if actual := w.Header().Get("Content-Length"); actual != "" {
+		t.Errorf("expected no Content-Length, got %q", actual)
+	}
+	if !w.Flushed {
+		t.Errorf("expected response to be flushed")
+	}
+
+	expect := "retry: 1000\n\nid: 42\ndata: first\n\nevent: update\nid: 43\ndata: second\n\n"
+	if actual := w.Body.String(); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+
+	if err := stream.Send(Event{Data: "late"}); !errors.Is(err, ErrEventStreamClosed) {
+		t.Errorf("Send: expected ErrEventStreamClosed, got %v", err)
+	}
+	select {
+	case <-stream.Done():
+	default:
+		t.Errorf("expected Done to be closed")
+	}
+}
+
+func TestEventStream_Heartbeat(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	stream := NewEventStream(req, &EventStreamOptions{Heartbeat: 5 * time.Millisecond})
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		stream.Close()
+	}()
+
+	w := httptest.NewRecorder()
+	if err := NewBuilder().WithEventStream(stream).Build().ServeRequest(w, req); err != nil {
+		t.Fatalf("ServeRequest failed: %v", err)
+	}
+	if !strings.HasPrefix(w.Body.String(), ":\n\n") {
+		t.Errorf("expected heartbeat comments, got %q", w.Body.String())
+	}
+}
+
+func TestEventStream_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	stream := NewEventStream(req, &EventStreamOptions{Heartbeat: -1})
+
+	sendErr := make(chan error, 1)
+	go func() {
+		if err := stream.Send(Event{Data: "only"}); err != nil {
+			sendErr <- err
+			return
+		}
+		cancel()
+		<-stream.Done()
+		sendErr <- stream.Send(Event{Data: "never"})
+	}()
+
+	w := httptest.NewRecorder()
+	if err := NewBuilder().WithEventStream(stream).Build().ServeRequest(w, req); err != nil {
+		t.Fatalf("ServeRequest failed: %v", err)
+	}
+	if expect, actual := "data: only\n\n", w.Body.String(); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+	if err := <-sendErr; err == nil {
+		t.Errorf("Send after cancel: expected error, got nil")
+	}
+}
+
+func TestEventStream_Copy(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	stream := NewEventStream(req, &EventStreamOptions{Heartbeat: -1})
+
+	go func() {
+		defer stream.Close()
+		_ = stream.Send(Event{Data: "first"})
+		_ = stream.Send(Event{Data: "second"})
+	}()
+
+	resp := NewBuilder().WithEventStream(stream).Build()
+	dupe, err := resp.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	// Closing the original must not stop the stream while the copy is
+	// still open.
+	if err := resp.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	select {
+	case <-stream.Done():
+		t.Errorf("expected Done to remain open while a copy is open")
+	default:
+	}
+
+	w := httptest.NewRecorder()
+	if err := dupe.ServeRequest(w, req); err != nil {
+		t.Fatalf("ServeRequest failed: %v", err)
+	}
+	if expect, actual := "data: first\n\ndata: second\n\n", w.Body.String(); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+	<-stream.Done()
+}
@@ -0,0 +1,272 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/chronos-tachyon/assert"
+
+	"github.com/chronos-tachyon/morehttp/body"
+)
+
+// DefaultJSONStreamFlushEvery is the default value of
+// JSONStreamOptions.FlushEvery.
+const DefaultJSONStreamFlushEvery = 64
+
+// JSONIterator yields the items of a JSON stream, one at a time.
+//
+// Next returns the next item, or io.EOF once there are no more items.  Any
+// other error ends the stream with a terminal error record.
+//
+// If the JSONIterator also implements io.Closer, then Close is called exactly
+// once, when the stream ends or when the Response is closed without being
+// served.
+//
+type JSONIterator interface {
+	Next() (interface{}, error)
+}
+
+// JSONIteratorFunc adapts a function to the JSONIterator interface.
+type JSONIteratorFunc func() (interface{}, error)
+
+// Next calls the function.
+func (fn JSONIteratorFunc) Next() (interface{}, error) {
+	return fn()
+}
+
+// JSONStreamFormat selects the framing used by WithJSONStream.
+type JSONStreamFormat uint8
+
+const (
+	// NDJSON frames each item as a single line of JSON, terminated by LF,
+	// with media type "application/x-ndjson".
+	NDJSON JSONStreamFormat = iota
+
+	// JSONSeq frames each item as an RFC 7464 JSON text sequence record,
+	// i.e. RS, JSON, LF, with media type "application/json-seq".
+	JSONSeq
+)
+
+// MediaType returns the media type for this framing.
+func (format JSONStreamFormat) MediaType() string {
+	if format == JSONSeq {
+		return "application/json-seq"
+	}
+	return "application/x-ndjson"
+}
+
+// JSONStreamOptions holds options for WithJSONStream.
+type JSONStreamOptions struct {
+	// Format selects the framing.  The default is NDJSON.
+	Format JSONStreamFormat
+
+	// FlushEvery is the number of items between flushes.  If zero,
+	// DefaultJSONStreamFlushEvery is used; if negative, the stream is
+	// flushed only when FlushInterval has elapsed.
+	FlushEvery int
+
+	// FlushInterval, if positive, also causes a flush after any item which
+	// is written this long after the previous flush.
+	FlushInterval time.Duration
+
+	// ErrorRecord converts an error from the JSONIterator into the
+	// terminal error record.  If nil, the record is {"error": err.Error()}.
+	ErrorRecord func(err error) interface{}
+}
+
+type jsonStreamError struct {
+	Error string `json:"error"`
+}
+
+func defaultErrorRecord(err error) interface{} {
+	return jsonStreamError{Error: err.Error()}
+}
+
+// WithJSONStream associates a Body with this Builder which lazily encodes the
+// items yielded by the given JSONIterator as a stream of JSON values.
+//
+// Items are encoded as if by WithJSON, but are only pulled from the
+// JSONIterator as the client consumes them.  If the JSONIterator fails, or if
+// an item cannot be encoded, then a terminal error record is written and the
+// stream ends.
+//
+// The options argument MAY be nil, in which case defaults are used.
+//
+// This method also adds the header "Content-Type: application/x-ndjson" or
+// "Content-Type: application/json-seq", depending on the format.
+//
+func (builder *Builder) WithJSONStream(iter JSONIterator, opts *JSONStreamOptions) *Builder {
+	assert.NotNil(&iter)
+
+	if opts == nil {
+		opts = &JSONStreamOptions{}
+	}
+
+	s := &jsonStream{
+		iter:          iter,
+		format:        opts.Format,
+		flushEvery:    opts.FlushEvery,
+		flushInterval: opts.FlushInterval,
+		errorRecord:   opts.ErrorRecord,
+		bodies:        1,
+	}
+	if s.flushEvery == 0 {
+		s.flushEvery = DefaultJSONStreamFlushEvery
+	}
+	if s.errorRecord == nil {
+		s.errorRecord = defaultErrorRecord
+	}
+
+	builder.body = &jsonStreamBody{Body: body.FromFunc(s.run), s: s}
+	hdrs := builder.Headers()
+	hdrs.Set("Content-Type", s.format.MediaType())
+	return builder
+}
+
+type jsonStream struct {
+	iter          JSONIterator
+	format        JSONStreamFormat
+	flushEvery    int
+	flushInterval time.Duration
+	errorRecord   func(error) interface{}
+
+	mu      sync.Mutex
+	bodies  int
+	started bool
+	done    bool
+}
+
+// begin marks the stream as started, returning false if it was abandoned
+// first.
+func (s *jsonStream) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done {
+		return false
+	}
+	s.started = true
+	return true
+}
+
+// addBody records a new copy of the stream's Body.
+func (s *jsonStream) addBody() {
+	s.mu.Lock()
+	s.bodies++
+	s.mu.Unlock()
+}
+
+// abandon is called as each copy of the stream's Body is closed.  Once the
+// last one is closed, it closes the JSONIterator if the stream has not
+// started.  Otherwise, the goroutine running the stream closes it once Next
+// returns, so that Close never races with Next.
+func (s *jsonStream) abandon() {
+	s.mu.Lock()
+	s.bodies--
+	if s.bodies > 0 {
+		s.mu.Unlock()
+		return
+	}
+	started := s.started
+	s.done = true
+	s.mu.Unlock()
+
+	if !started {
+		s.closeIter()
+	}
+}
+
+func (s *jsonStream) closeIter() {
+	if c, ok := s.iter.(io.Closer); ok {
+		_ = c.Close()
+	}
+}
+
+func (s *jsonStream) encode(buf *bytes.Buffer, v interface{}) error {
+	buf.Reset()
+	if s.format == JSONSeq {
+		buf.WriteByte(0x1e)
+	}
+	e := json.NewEncoder(buf)
+	e.SetEscapeHTML(false)
+	return e.Encode(v)
+}
+
+func (s *jsonStream) run(w *body.PipeWriter) error {
+	if !s.begin() {
+		return nil
+	}
+	defer s.closeIter()
+
+	var buf bytes.Buffer
+	var count int
+	lastFlush := time.Now()
+
+	for {
+		item, err := s.iter.Next()
+		if err == io.EOF {
+			return w.Flush()
+		}
+		if err == nil {
+			err = s.encode(&buf, item)
+		}
+		if err != nil {
+			if err2 := s.encode(&buf, s.errorRecord(err)); err2 != nil {
+				return err2
+			}
+			if _, err2 := w.Write(buf.Bytes()); err2 != nil {
+				return err2
+			}
+			return w.Flush()
+		}
+
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+
+		count++
+		now := time.Now()
+		if (s.flushEvery > 0 && count%s.flushEvery == 0) || (s.flushInterval > 0 && now.Sub(lastFlush) >= s.flushInterval) {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			lastFlush = now
+		}
+	}
+}
+
+type jsonStreamBody struct {
+	body.Body
+	s *jsonStream
+}
+
+func (b *jsonStreamBody) WriteTo(w io.Writer) (int64, error) {
+	return b.Body.(io.WriterTo).WriteTo(w)
+}
+
+func (b *jsonStreamBody) Close() error {
+	err := b.Body.Close()
+	if !errors.Is(err, fs.ErrClosed) {
+		b.s.abandon()
+	}
+	return err
+}
+
+func (b *jsonStreamBody) Copy() (body.Body, error) {
+	dupe, err := b.Body.Copy()
+	if err != nil || dupe == body.AlreadyClosed() {
+		return dupe, err
+	}
+	b.s.addBody()
+	return &jsonStreamBody{Body: dupe, s: b.s}, nil
+}
+
+var (
+	_ body.Body   = (*jsonStreamBody)(nil)
+	_ io.WriterTo = (*jsonStreamBody)(nil)
+)
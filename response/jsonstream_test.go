@@ -0,0 +1,200 @@
+package response
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type sliceIteratorForTest struct {
+	items  []interface{}
+	err    error
+	closed int
+}
+
+func (iter *sliceIteratorForTest) Next() (interface{}, error) {
+	if len(iter.items) == 0 {
+		if iter.err != nil {
+			return nil, iter.err
+		}
+		return nil, io.EOF
+	}
+	item := iter.items[0]
+	iter.items = iter.items[1:]
+	return item, nil
+}
+
+func (iter *sliceIteratorForTest) Close() error {
+	iter.closed++
+	return nil
+}
+
+type flushCounterForTest struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (w *flushCounterForTest) Flush() {
+	w.flushes++
+	w.ResponseRecorder.Flush()
+}
+
+func TestWithJSONStream(t *testing.T) {
+	iter := &sliceIteratorForTest{
+		items: []interface{}{
+			map[string]int{"n": 1},
+			map[string]string{"html": "<b>"},
+			[]int{2, 3},
+		},
+	}
+
+	resp := NewBuilder().WithJSONStream(iter, &JSONStreamOptions{FlushEvery: 2}).Build()
+	if actual := resp.Headers().Get("Content-Length"); actual != "" {
+		t.Errorf("expected no Content-Length, got %q", actual)
+	}
+
+	w := &flushCounterForTest{ResponseRecorder: httptest.NewRecorder()}
+	if err := resp.ServeRequest(w, httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+		t.Fatalf("ServeRequest failed: %v", err)
+	}
+
+	if expect, actual := "application/x-ndjson", w.Header().Get("Content-Type"); expect != actual {
+		t.Errorf("expected Content-Type %q, got %q", expect, actual)
+	}
+	if expect, actual := "{\"n\":1}\n{\"html\":\"<b>\"}\n[2,3]\n", w.Body.String(); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+	if expect := 2; w.flushes != expect {
+		t.Errorf("expected %d flushes, got %d", expect, w.flushes)
+	}
+	if expect := 1; iter.closed != expect {
+		t.Errorf("expected %d calls to Close, got %d", expect, iter.closed)
+	}
+}
+
+func TestWithJSONStream_Seq(t *testing.T) {
+	iter := &sliceIteratorForTest{items: []interface{}{1, "two"}}
+
+	w := httptest.NewRecorder()
+	resp := NewBuilder().WithJSONStream(iter, &JSONStreamOptions{Format: JSONSeq}).Build()
+	if err := resp.ServeRequest(w, httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+		t.Fatalf("ServeRequest failed: %v", err)
+	}
+
+	if expect, actual := "application/json-seq", w.Header().Get("Content-Type"); expect != actual {
+		t.Errorf("expected Content-Type %q, got %q", expect, actual)
+	}
+	if expect, actual := "\x1e1\n\x1e\"two\"\n", w.Body.String(); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+}
+
+func TestWithJSONStream_Error(t *testing.T) {
+	errTest := errors.New("cursor lost")
+
+	t.Run("Default", func(t *testing.T) {
+		iter := &sliceIteratorForTest{items: []interface{}{1}, err: errTest}
+
+		w := httptest.NewRecorder()
+		resp := NewBuilder().WithJSONStream(iter, nil).Build()
+		if err := resp.ServeRequest(w, httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+			t.Fatalf("ServeRequest failed: %v", err)
+		}
+		if expect, actual := "1\n{\"error\":\"cursor lost\"}\n", w.Body.String(); expect != actual {
+			t.Errorf("expected body %q, got %q", expect, actual)
+		}
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		iter := &sliceIteratorForTest{err: errTest}
+		opts := &JSONStreamOptions{
+			ErrorRecord: func(err error) interface{} {
+				return map[string]interface{}{"ok": false, "reason": err.Error()}
+			},
+		}
+
+		w := httptest.NewRecorder()
+		resp := NewBuilder().WithJSONStream(iter, opts).Build()
+		if err := resp.ServeRequest(w, httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+			t.Fatalf("ServeRequest failed: %v", err)
+		}
+		if expect, actual := "{\"ok\":false,\"reason\":\"cursor lost\"}\n", w.Body.String(); expect != actual {
+			t.Errorf("expected body %q, got %q", expect, actual)
+		}
+	})
+
+	t.Run("Unencodable", func(t *testing.T) {
+		iter := &sliceIteratorForTest{items: []interface{}{1, make(chan int), 3}}
+
+		w := httptest.NewRecorder()
+		resp := NewBuilder().WithJSONStream(iter, nil).Build()
+		if err := resp.ServeRequest(w, httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+			t.Fatalf("ServeRequest failed: %v", err)
+		}
+		if expect, actual := "1\n{\"error\":\"json: unsupported type: chan int\"}\n", w.Body.String(); expect != actual {
+			t.Errorf("expected body %q, got %q", expect, actual)
+		}
+	})
+}
+
+func TestWithJSONStream_CloseUnserved(t *testing.T) {
+	iter := &sliceIteratorForTest{items: []interface{}{1}}
+	resp := NewBuilder().WithJSONStream(iter, nil).Build()
+	if err := resp.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if expect := 1; iter.closed != expect {
+		t.Errorf("expected %d calls to Close, got %d", expect, iter.closed)
+	}
+	if expect := 1; len(iter.items) != expect {
+		t.Errorf("expected %d unconsumed items, got %d", expect, len(iter.items))
+	}
+}
+
+func TestWithJSONStream_Copy(t *testing.T) {
+	iter := &sliceIteratorForTest{items: []interface{}{1, "two"}}
+	builder := NewBuilder().WithJSONStream(iter, nil)
+	dupe, err := builder.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	const expect = "1\n\"two\"\n"
+	for index, resp := range []*Response{builder.Build(), dupe.Build()} {
+		w := httptest.NewRecorder()
+		if err := resp.ServeRequest(w, httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+			t.Fatalf("%d: ServeRequest failed: %v", index, err)
+		}
+		if actual := w.Body.String(); expect != actual {
+			t.Errorf("%d: expected body %q, got %q", index, expect, actual)
+		}
+	}
+	if expect := 1; iter.closed != expect {
+		t.Errorf("expected %d calls to Close, got %d", expect, iter.closed)
+	}
+}
+
+func TestWithJSONStream_CloseUnservedCopy(t *testing.T) {
+	iter := &sliceIteratorForTest{items: []interface{}{1}}
+	resp := NewBuilder().WithJSONStream(iter, nil).Build()
+	dupe, err := resp.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	if err := resp.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if expect := 0; iter.closed != expect {
+		t.Errorf("expected %d calls to Close while a copy is open, got %d", expect, iter.closed)
+	}
+
+	if err := dupe.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if expect := 1; iter.closed != expect {
+		t.Errorf("expected %d calls to Close, got %d", expect, iter.closed)
+	}
+}
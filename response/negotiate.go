@@ -0,0 +1,80 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/chronos-tachyon/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/chronos-tachyon/morehttp/body"
+	"github.com/chronos-tachyon/morehttp/internal/negotiate"
+)
+
+// ErrNotAcceptable is the error which is passed to the PageGenerator when
+// none of the available representations are acceptable to the client.
+var ErrNotAcceptable = errors.New("none of the available media types are acceptable")
+
+const (
+	mediaTypeJSON      = "application/json"
+	mediaTypeProto     = "application/vnd.google.protobuf"
+	mediaTypeProtoText = "text/plain"
+)
+
+// WithProtoJSON converts the given value to a JSON Body using the canonical
+// protobuf JSON mapping, then associates it with this Builder.
+//
+// The MarshalOptions argument MAY be nil, in which case sensible defaults are
+// used.
+//
+// This method also adds the header "Content-Type: application/json".
+//
+func (builder *Builder) WithProtoJSON(msg proto.Message, o *protojson.MarshalOptions) *Builder {
+	builder.body = body.FromProtoJSON(msg, o)
+	hdrs := builder.Headers()
+	hdrs.Set("Content-Type", mediaTypeJSON)
+	return builder
+}
+
+// WithNegotiatedMessage converts the given value to a Body in the format which
+// best suits the given request's Accept header, then associates it with this
+// Builder.
+//
+// If the value is a proto.Message, then the candidates are JSON (as if by
+// WithProtoJSON), binary protobuf (as if by WithProto), and text protobuf (as
+// if by WithProtoText), in that order of preference.  Otherwise, the only
+// candidate is JSON (as if by WithJSON).
+//
+// If none of the candidates are acceptable, then this Builder is populated
+// with a "406 Not Acceptable" error page carrying ErrNotAcceptable.
+//
+// In all cases, this method also adds the header "Vary: Accept".
+//
+func (builder *Builder) WithNegotiatedMessage(req *http.Request, v interface{}) *Builder {
+	assert.NotNil(&req)
+
+	msg, isProto := v.(proto.Message)
+
+	available := []string{mediaTypeJSON}
+	if isProto {
+		available = append(available, mediaTypeProto, mediaTypeProtoText)
+	}
+
+	mediaType, ok := negotiate.SelectMediaType(req.Header.Values("Accept"), available)
+	switch {
+	case !ok:
+		builder.ErrorPage(http.StatusNotAcceptable, ErrNotAcceptable)
+	case mediaType == mediaTypeProto:
+		builder.WithProto(msg)
+	case mediaType == mediaTypeProtoText:
+		builder.WithProtoText(msg, nil)
+	case isProto:
+		builder.WithProtoJSON(msg, nil)
+	default:
+		builder.WithJSON(v)
+	}
+
+	negotiate.AddVary(builder.Headers(), "Accept")
+	return builder
+}
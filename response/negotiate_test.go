@@ -0,0 +1,69 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestBuilder_WithNegotiatedMessage(t *testing.T) {
+	msg := wrapperspb.String("abc")
+	raw, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatalf("proto.Marshal failed: %v", err)
+	}
+	text, err := prototext.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(msg)
+	if err != nil {
+		t.Fatalf("prototext.Marshal failed: %v", err)
+	}
+
+	type testRow struct {
+		Value       interface{}
+		Accept      string
+		Status      int
+		ContentType string
+		Data        string
+	}
+
+	testData := []testRow{
+		{msg, "", http.StatusOK, "application/json", `"abc"`},
+		{msg, "*/*", http.StatusOK, "application/json", `"abc"`},
+		{msg, "application/vnd.google.protobuf", http.StatusOK, "application/vnd.google.protobuf", string(raw)},
+		{msg, "application/json;q=0.5, application/*", http.StatusOK, "application/vnd.google.protobuf", string(raw)},
+		{msg, "text/*", http.StatusOK, "text/plain; charset=utf-8", string(text)},
+		{msg, "image/png", http.StatusNotAcceptable, "", ""},
+		{[]int{1, 2}, "", http.StatusOK, "application/json", "[1,2]\n"},
+		{[]int{1, 2}, "application/vnd.google.protobuf", http.StatusNotAcceptable, "", ""},
+	}
+
+	for _, row := range testData {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if row.Accept != "" {
+			req.Header.Set("Accept", row.Accept)
+		}
+
+		builder := NewBuilder().WithNegotiatedMessage(req, row.Value)
+		if expect, actual := "Accept", builder.Headers().Get("Vary"); expect != actual {
+			t.Errorf("%q: expected Vary %q, got %q", row.Accept, expect, actual)
+		}
+
+		result := serveForTest(t, builder.Build(), req)
+		if result.StatusCode != row.Status {
+			t.Errorf("%q: expected status %d, got %d", row.Accept, row.Status, result.StatusCode)
+			continue
+		}
+		if row.Status != http.StatusOK {
+			continue
+		}
+		if expect, actual := row.ContentType, result.Header.Get("Content-Type"); expect != actual {
+			t.Errorf("%q: expected Content-Type %q, got %q", row.Accept, expect, actual)
+		}
+		if expect, actual := row.Data, readAllForTest(t, result.Body); expect != actual {
+			t.Errorf("%q: expected body %q, got %q", row.Accept, expect, actual)
+		}
+	}
+}
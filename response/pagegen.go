@@ -32,9 +32,16 @@ func (gen *defaultPageGenerator) GenerateRedirectPage(code int, location string)
 }
 
 func (gen *defaultPageGenerator) GenerateErrorPage(code int, err error) (http.Header, body.Body) {
+	return gen.GenerateErrorPageWithRequestID(code, err, "")
+}
+
+func (gen *defaultPageGenerator) GenerateErrorPageWithRequestID(code int, err error, requestID string) (http.Header, body.Body) {
 	statusCode := strconv.Itoa(code)
 	statusText := http.StatusText(code)
 	statusLine := statusCode + " " + statusText + "\r\n"
+	if requestID != "" {
+		statusLine += "Request ID: " + requestID + "\r\n"
+	}
 	raw := []byte(statusLine)
 
 	headers := make(http.Header, 16)
@@ -45,4 +52,21 @@ func (gen *defaultPageGenerator) GenerateErrorPage(code int, err error) (http.He
 	return headers, body.FromBytes(raw)
 }
 
+// RequestIDPageGenerator is an optional interface which a PageGenerator may
+// implement to include a request ID in its error pages, so that users can
+// quote the ID when contacting support.
+type RequestIDPageGenerator interface {
+	PageGenerator
+	GenerateErrorPageWithRequestID(code int, err error, requestID string) (http.Header, body.Body)
+}
+
+func generateErrorPage(gen PageGenerator, code int, err error, requestID string) (http.Header, body.Body) {
+	if x, ok := gen.(RequestIDPageGenerator); ok && requestID != "" {
+		return x.GenerateErrorPageWithRequestID(code, err, requestID)
+	}
+	return gen.GenerateErrorPage(code, err)
+}
+
 var DefaultPageGenerator PageGenerator = &defaultPageGenerator{}
+
+var _ RequestIDPageGenerator = (*defaultPageGenerator)(nil)
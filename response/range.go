@@ -0,0 +1,268 @@
+package response
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chronos-tachyon/morehttp/body"
+)
+
+// ErrRangeNotSatisfiable is the error which is passed to the PageGenerator
+// when none of the ranges in a request's Range header can be satisfied.
+var ErrRangeNotSatisfiable = errors.New("none of the requested byte ranges can be satisfied")
+
+var headerAcceptRanges = http.CanonicalHeaderKey("Accept-Ranges")
+
+type byteRange struct {
+	start  int64
+	length int64
+}
+
+func (r byteRange) contentRange(size int64) string {
+	return "bytes " + strconv.FormatInt(r.start, 10) + "-" + strconv.FormatInt(r.start+r.length-1, 10) + "/" + strconv.FormatInt(size, 10)
+}
+
+// parseRange parses the value of a Range header for a representation of the
+// given size, returning the list of satisfiable ranges.
+//
+// The boolean result is false if the header is syntactically invalid or uses
+// a range unit other than "bytes", in which case the header must be ignored.
+// An empty list with a true boolean result means that the header is valid but
+// no range within it is satisfiable.
+//
+func parseRange(str string, size int64) ([]byteRange, bool) {
+	const prefix = "bytes="
+
+	str = strings.TrimSpace(str)
+	if len(str) < len(prefix) || !strings.EqualFold(str[:len(prefix)], prefix) {
+		return nil, false
+	}
+
+	var list []byteRange
+	numSpecs := 0
+	for _, spec := range strings.Split(str[len(prefix):], ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		numSpecs++
+
+		i := strings.IndexByte(spec, '-')
+		if i < 0 {
+			return nil, false
+		}
+
+		first := strings.TrimSpace(spec[:i])
+		last := strings.TrimSpace(spec[i+1:])
+
+		if first == "" {
+			n, ok := parseRangeInt(last)
+			if !ok {
+				return nil, false
+			}
+			if n == 0 || size == 0 {
+				continue
+			}
+			if n > size {
+				n = size
+			}
+			list = append(list, byteRange{start: size - n, length: n})
+			continue
+		}
+
+		start, ok := parseRangeInt(first)
+		if !ok {
+			return nil, false
+		}
+
+		end := size - 1
+		if last != "" {
+			end, ok = parseRangeInt(last)
+			if !ok || end < start {
+				return nil, false
+			}
+			if end >= size {
+				end = size - 1
+			}
+		}
+
+		if start >= size {
+			continue
+		}
+
+		list = append(list, byteRange{start: start, length: end - start + 1})
+	}
+
+	if numSpecs == 0 {
+		return nil, false
+	}
+
+	return list, true
+}
+
+func parseRangeInt(str string) (int64, bool) {
+	if str == "" {
+		return 0, false
+	}
+	for _, ch := range []byte(str) {
+		if ch < '0' || ch > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// checkIfRange returns true iff the If-Range header (if any) permits a
+// partial response.
+//
+// Per RFC 7233 section 3.2, an HTTP-date only matches if Last-Modified is a
+// strong validator, i.e. if it is at least one second earlier than the
+// response's Date header.  Otherwise, the representation may have changed
+// twice within the same second, and the client could splice together bytes
+// from two different versions.
+//
+func checkIfRange(req *http.Request, hdrs http.Header) bool {
+	ifRange := strings.TrimSpace(req.Header.Get("If-Range"))
+	if ifRange == "" {
+		return true
+	}
+
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, `W/"`) {
+		want, ok := parseETag(ifRange)
+		if !ok {
+			return false
+		}
+		have, ok := parseETag(hdrs.Get("Etag"))
+		if !ok {
+			return false
+		}
+		return want.strongMatch(have)
+	}
+
+	want, err := http.ParseTime(ifRange)
+	if err != nil {
+		return false
+	}
+	have, err := http.ParseTime(hdrs.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	date, err := http.ParseTime(hdrs.Get("Date"))
+	if err != nil || date.Sub(have) < time.Second {
+		return false
+	}
+	return want.Equal(have)
+}
+
+func (resp *Response) applyRange(req *http.Request, hdrs http.Header, b body.Body) (int, http.Header, body.Body) {
+	size := b.BytesRemaining()
+	if _, ok := b.(io.ReaderAt); !ok || size < 0 {
+		return http.StatusOK, hdrs, b
+	}
+
+	hdrs = copyHeaders(hdrs)
+	if hdrs == nil {
+		hdrs = make(http.Header, 16)
+	}
+	if _, found := hdrs[headerAcceptRanges]; !found {
+		hdrs.Set(headerAcceptRanges, "bytes")
+	}
+
+	rangeHeader := req.Header.Get("Range")
+	if rangeHeader == "" || !checkIfRange(req, hdrs) {
+		return http.StatusOK, hdrs, b
+	}
+
+	ranges, ok := parseRange(rangeHeader, size)
+	if !ok {
+		return http.StatusOK, hdrs, b
+	}
+
+	if len(ranges) == 0 {
+		_ = b.Close()
+		h, eb := generateErrorPage(resp.PageGenerator(), http.StatusRequestedRangeNotSatisfiable, ErrRangeNotSatisfiable, RequestIDFromContext(req.Context()))
+		h.Set("Content-Range", "bytes */"+strconv.FormatInt(size, 10))
+		return http.StatusRequestedRangeNotSatisfiable, h, eb
+	}
+
+	// Refuse to serve more bytes than the full representation contains,
+	// as such requests are typically abusive.
+	var sum int64
+	for _, r := range ranges {
+		sum += r.length
+	}
+	if sum > size {
+		return http.StatusOK, hdrs, b
+	}
+
+	var base int64
+	if s, ok := b.(io.Seeker); ok {
+		var err error
+		base, err = s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return http.StatusOK, hdrs, b
+		}
+	}
+
+	if len(ranges) == 1 {
+		r := ranges[0]
+		sliced, err := body.Slice(b, base+r.start, r.length)
+		if err != nil {
+			return http.StatusOK, hdrs, b
+		}
+		_ = b.Close()
+
+		hdrs.Set("Content-Range", r.contentRange(size))
+		hdrs.Set("Content-Length", strconv.FormatInt(r.length, 10))
+		return http.StatusPartialContent, hdrs, sliced
+	}
+
+	contentType := hdrs.Get("Content-Type")
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+
+	parts := make([]body.Body, 0, 2*len(ranges)+1)
+	for index, r := range ranges {
+		sliced, err := body.Slice(b, base+r.start, r.length)
+		if err != nil {
+			for _, part := range parts {
+				_ = part.Close()
+			}
+			return http.StatusOK, hdrs, b
+		}
+
+		var sb strings.Builder
+		if index > 0 {
+			sb.WriteString("\r\n")
+		}
+		sb.WriteString("--")
+		sb.WriteString(boundary)
+		sb.WriteString("\r\n")
+		if contentType != "" {
+			sb.WriteString("Content-Type: ")
+			sb.WriteString(contentType)
+			sb.WriteString("\r\n")
+		}
+		sb.WriteString("Content-Range: ")
+		sb.WriteString(r.contentRange(size))
+		sb.WriteString("\r\n\r\n")
+
+		parts = append(parts, body.FromString(sb.String()), sliced)
+	}
+	parts = append(parts, body.FromString("\r\n--"+boundary+"--\r\n"))
+	_ = b.Close()
+
+	multi := body.Concat(parts...)
+	hdrs.Set("Content-Type", "multipart/byteranges; boundary="+boundary)
+	hdrs.Set("Content-Length", strconv.FormatInt(multi.BytesRemaining(), 10))
+	hdrs.Del("Content-Range")
+	return http.StatusPartialContent, hdrs, multi
+}
@@ -0,0 +1,262 @@
+package response
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chronos-tachyon/morehttp/body"
+)
+
+func TestParseRange(t *testing.T) {
+	type testRow struct {
+		Input  string
+		Size   int64
+		Expect []byteRange
+		OK     bool
+	}
+
+	testData := []testRow{
+		{"", 10, nil, false},
+		{"bytes=", 10, nil, false},
+		{"items=0-1", 10, nil, false},
+		{"bytes=0-4", 10, []byteRange{{0, 5}}, true},
+		{"Bytes=0-4", 10, []byteRange{{0, 5}}, true},
+		{"bytes=5-", 10, []byteRange{{5, 5}}, true},
+		{"bytes=-3", 10, []byteRange{{7, 3}}, true},
+		{"bytes=-30", 10, []byteRange{{0, 10}}, true},
+		{"bytes=8-30", 10, []byteRange{{8, 2}}, true},
+		{"bytes=0-0, 2-3, -1", 10, []byteRange{{0, 1}, {2, 2}, {9, 1}}, true},
+		{"bytes=10-20", 10, nil, true},
+		{"bytes=-0", 10, nil, true},
+		{"bytes=10-20, 2-3", 10, []byteRange{{2, 2}}, true},
+		{"bytes=4-3", 10, nil, false},
+		{"bytes=a-b", 10, nil, false},
+		{"bytes=1", 10, nil, false},
+		{"bytes=+1-2", 10, nil, false},
+	}
+
+	for _, row := range testData {
+		list, ok := parseRange(row.Input, row.Size)
+		if ok != row.OK {
+			t.Errorf("parseRange(%q, %d): expected ok=%v, got ok=%v", row.Input, row.Size, row.OK, ok)
+			continue
+		}
+		if !reflect.DeepEqual(list, row.Expect) {
+			t.Errorf("parseRange(%q, %d): expected %v, got %v", row.Input, row.Size, row.Expect, list)
+		}
+	}
+}
+
+func serveForTest(t *testing.T, resp *Response, req *http.Request) *http.Response {
+	t.Helper()
+	w := httptest.NewRecorder()
+	if err := resp.ServeRequest(w, req); err != nil {
+		t.Fatalf("ServeRequest failed: %v", err)
+	}
+	return w.Result()
+}
+
+func readAllForTest(t *testing.T, r io.Reader) string {
+	t.Helper()
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	return string(raw)
+}
+
+func TestServeRequest_Range(t *testing.T) {
+	const content = "0123456789abcdef"
+	modTime := time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	newResponse := func() *Response {
+		return NewBuilder().
+			WithContentType("text/plain").
+			WithETag("v1", true).
+			WithLastModified(modTime).
+			WithHeader("Date", modTime.Add(time.Hour).Format(http.TimeFormat), false).
+			WithBody(body.FromString(content)).
+			Build()
+	}
+
+	t.Run("NoRange", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		result := serveForTest(t, newResponse(), req)
+		if expect := http.StatusOK; result.StatusCode != expect {
+			t.Errorf("expected status %d, got %d", expect, result.StatusCode)
+		}
+		if expect, actual := "bytes", result.Header.Get("Accept-Ranges"); expect != actual {
+			t.Errorf("expected Accept-Ranges %q, got %q", expect, actual)
+		}
+		if actual := readAllForTest(t, result.Body); content != actual {
+			t.Errorf("expected body %q, got %q", content, actual)
+		}
+	})
+
+	t.Run("Single", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Range", "bytes=2-5")
+		result := serveForTest(t, newResponse(), req)
+		if expect := http.StatusPartialContent; result.StatusCode != expect {
+			t.Errorf("expected status %d, got %d", expect, result.StatusCode)
+		}
+		if expect, actual := "bytes 2-5/16", result.Header.Get("Content-Range"); expect != actual {
+			t.Errorf("expected Content-Range %q, got %q", expect, actual)
+		}
+		if expect, actual := "4", result.Header.Get("Content-Length"); expect != actual {
+			t.Errorf("expected Content-Length %q, got %q", expect, actual)
+		}
+		if expect, actual := "2345", readAllForTest(t, result.Body); expect != actual {
+			t.Errorf("expected body %q, got %q", expect, actual)
+		}
+	})
+
+	t.Run("Multiple", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Range", "bytes=0-1, -2")
+		result := serveForTest(t, newResponse(), req)
+		if expect := http.StatusPartialContent; result.StatusCode != expect {
+			t.Errorf("expected status %d, got %d", expect, result.StatusCode)
+		}
+
+		mediaType, params, err := mime.ParseMediaType(result.Header.Get("Content-Type"))
+		if err != nil {
+			t.Fatalf("ParseMediaType failed: %v", err)
+		}
+		if expect := "multipart/byteranges"; mediaType != expect {
+			t.Errorf("expected media type %q, got %q", expect, mediaType)
+		}
+
+		raw := readAllForTest(t, result.Body)
+		if expect, actual := strconv.Itoa(len(raw)), result.Header.Get("Content-Length"); expect != actual {
+			t.Errorf("expected Content-Length %q, got %q", expect, actual)
+		}
+
+		mr := multipart.NewReader(strings.NewReader(raw), params["boundary"])
+		expectParts := []struct {
+			ContentRange string
+			Data         string
+		}{
+			{"bytes 0-1/16", "01"},
+			{"bytes 14-15/16", "ef"},
+		}
+		for _, expectPart := range expectParts {
+			part, err := mr.NextPart()
+			if err != nil {
+				t.Fatalf("NextPart failed: %v", err)
+			}
+			if expect, actual := "text/plain", part.Header.Get("Content-Type"); expect != actual {
+				t.Errorf("expected part Content-Type %q, got %q", expect, actual)
+			}
+			if expect, actual := expectPart.ContentRange, part.Header.Get("Content-Range"); expect != actual {
+				t.Errorf("expected part Content-Range %q, got %q", expect, actual)
+			}
+			if expect, actual := expectPart.Data, readAllForTest(t, part); expect != actual {
+				t.Errorf("expected part body %q, got %q", expect, actual)
+			}
+		}
+		if _, err := mr.NextPart(); err != io.EOF {
+			t.Errorf("expected io.EOF after last part, got %v", err)
+		}
+	})
+
+	t.Run("Unsatisfiable", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Range", "bytes=100-200")
+		result := serveForTest(t, newResponse(), req)
+		if expect := http.StatusRequestedRangeNotSatisfiable; result.StatusCode != expect {
+			t.Errorf("expected status %d, got %d", expect, result.StatusCode)
+		}
+		if expect, actual := "bytes */16", result.Header.Get("Content-Range"); expect != actual {
+			t.Errorf("expected Content-Range %q, got %q", expect, actual)
+		}
+	})
+
+	t.Run("UnsatisfiableWithRequestID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(ContextWithRequestID(req.Context(), "req-416"))
+		req.Header.Set("Range", "bytes=100-200")
+		result := serveForTest(t, newResponse(), req)
+		if expect := http.StatusRequestedRangeNotSatisfiable; result.StatusCode != expect {
+			t.Errorf("expected status %d, got %d", expect, result.StatusCode)
+		}
+		if expect, actual := "416 Requested Range Not Satisfiable\r\nRequest ID: req-416\r\n", readAllForTest(t, result.Body); expect != actual {
+			t.Errorf("expected body %q, got %q", expect, actual)
+		}
+	})
+
+	t.Run("IfRange", func(t *testing.T) {
+		type testRow struct {
+			IfRange string
+			Status  int
+		}
+
+		testData := []testRow{
+			{`"v1"`, http.StatusPartialContent},
+			{`"v2"`, http.StatusOK},
+			{`W/"v1"`, http.StatusOK},
+			{modTime.Format(http.TimeFormat), http.StatusPartialContent},
+			{modTime.Add(time.Second).Format(http.TimeFormat), http.StatusOK},
+		}
+
+		for _, row := range testData {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Range", "bytes=2-5")
+			req.Header.Set("If-Range", row.IfRange)
+			result := serveForTest(t, newResponse(), req)
+			if result.StatusCode != row.Status {
+				t.Errorf("If-Range %q: expected status %d, got %d", row.IfRange, row.Status, result.StatusCode)
+			}
+		}
+	})
+
+	t.Run("IfRangeWeakLastModified", func(t *testing.T) {
+		type testRow struct {
+			Date   string
+			Status int
+		}
+
+		// Last-Modified is only a strong validator if it is at least one
+		// second before Date.
+		testData := []testRow{
+			{"", http.StatusOK},
+			{modTime.Format(http.TimeFormat), http.StatusOK},
+			{modTime.Add(time.Second).Format(http.TimeFormat), http.StatusPartialContent},
+		}
+
+		for _, row := range testData {
+			builder := NewBuilder().
+				WithContentType("text/plain").
+				WithLastModified(modTime).
+				WithBody(body.FromString(content))
+			if row.Date != "" {
+				builder.WithHeader("Date", row.Date, false)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Range", "bytes=2-5")
+			req.Header.Set("If-Range", modTime.Format(http.TimeFormat))
+			result := serveForTest(t, builder.Build(), req)
+			if result.StatusCode != row.Status {
+				t.Errorf("Date %q: expected status %d, got %d", row.Date, row.Status, result.StatusCode)
+			}
+		}
+	})
+
+	t.Run("NotGET", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Range", "bytes=2-5")
+		result := serveForTest(t, newResponse(), req)
+		if expect := http.StatusOK; result.StatusCode != expect {
+			t.Errorf("expected status %d, got %d", expect, result.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+package response
+
+import (
+	"context"
+)
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx which carries the given request
+// ID.  Error pages generated while serving a request with this context include
+// the ID, if the PageGenerator implements RequestIDPageGenerator.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return ContextWithRequestIDFunc(ctx, func() string { return id })
+}
+
+// ContextWithRequestIDFunc is like ContextWithRequestID, except that the
+// request ID is obtained by calling fn each time it is needed.  This allows
+// the ID to be assigned after the context has been created.
+func ContextWithRequestIDFunc(ctx context.Context, fn func() string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, fn)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, or the empty
+// string if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	if fn, ok := ctx.Value(requestIDKey{}).(func() string); ok {
+		return fn()
+	}
+	return ""
+}
@@ -6,6 +6,8 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/chronos-tachyon/assert"
+
 	"github.com/chronos-tachyon/morehttp/body"
 )
 
@@ -13,10 +15,21 @@ var headerPush = http.CanonicalHeaderKey("Push")
 
 // Response represents an HTTP response.
 type Response struct {
-	code int
-	hdrs http.Header
-	body body.Body
-	err  error
+	gen      PageGenerator
+	code     int
+	hdrs     http.Header
+	body     body.Body
+	variants map[string]body.Body
+	err      error
+}
+
+// PageGenerator returns the PageGenerator which was associated with the
+// Builder that built this Response, or DefaultPageGenerator if not set.
+func (resp *Response) PageGenerator() PageGenerator {
+	if resp.gen == nil {
+		return DefaultPageGenerator
+	}
+	return resp.gen
 }
 
 // Status returns the HTTP status code of the response.
@@ -44,6 +57,15 @@ func (resp *Response) Body() body.Body {
 	return resp.body
 }
 
+// EncodedVariants returns the alternative Bodies which hold this Response's
+// content in other content codings, indexed by content coding.
+//
+// The caller MUST NOT modify the returned map or its contents.
+//
+func (resp *Response) EncodedVariants() map[string]body.Body {
+	return resp.variants
+}
+
 // Err returns the Go error which provoked this Response, if any.
 func (resp *Response) Err() error {
 	return resp.err
@@ -64,19 +86,117 @@ func (resp *Response) Copy() (*Response, error) {
 		return nil, err
 	}
 
+	variants2, err := copyVariants(resp.variants)
+	if err != nil {
+		_ = body2.Close()
+		return nil, err
+	}
+
 	out := &Response{
-		code: resp.code,
-		hdrs: resp.hdrs,
-		body: body2,
-		err:  resp.err,
+		gen:      resp.gen,
+		code:     resp.code,
+		hdrs:     resp.hdrs,
+		body:     body2,
+		variants: variants2,
+		err:      resp.err,
 	}
 	return out, nil
 }
 
+// ToBuilder returns a new Builder populated from this Response, so that the
+// Response can be modified and rebuilt.  Ownership of the Body and any encoded
+// variants passes to the Builder; afterward, this Response MUST NOT be served.
+//
+// The headers are deep-copied, except that Content-Length is dropped so that
+// Build can recompute it for whichever Body is eventually used.
+//
+func (resp *Response) ToBuilder() *Builder {
+	hdrs := copyHeaders(resp.hdrs)
+	if hdrs != nil {
+		hdrs.Del("Content-Length")
+	}
+
+	builder := &Builder{
+		gen:      resp.gen,
+		code:     resp.code,
+		hdrs:     hdrs,
+		body:     resp.body,
+		variants: resp.variants,
+		err:      resp.err,
+	}
+
+	resp.body = nil
+	resp.variants = nil
+	return builder
+}
+
+// Close releases the Response's Body and any encoded variants without
+// serving them.  Use this when a Response will never be served.
+func (resp *Response) Close() error {
+	closeVariants(resp.variants)
+	resp.variants = nil
+
+	if resp.body == nil {
+		return nil
+	}
+	return resp.body.Close()
+}
+
 // Serve serves the Response via the given ResponseWriter, consuming its Body.
+//
+// Encoded variants are never sent by this method; they are simply closed.
+//
 func (resp *Response) Serve(w http.ResponseWriter) error {
+	closeVariants(resp.variants)
+	resp.variants = nil
+	return serveImpl(w, resp.code, resp.hdrs, resp.body)
+}
+
+// ServeRequest serves the Response via the given ResponseWriter, consuming
+// its Body, in reply to the given Request.
+//
+// Unlike Serve, this method honors the request headers which allow the client
+// to ask for a subset of the response:
+//
+// - Accept-Encoding selects among the identity Body and any encoded variants,
+//   using q-values to rank them.  If a variant is chosen, Content-Encoding and
+//   Content-Length are set to match it, and the content coding is appended to
+//   the ETag.  The Bodies which are not sent are closed.
+//
+// - If-Match, If-Unmodified-Since, If-None-Match, and If-Modified-Since are
+//   evaluated for 2xx responses against the ETag and Last-Modified response
+//   headers, following the precedence rules of RFC 7232.  The result may be
+//   "304 Not Modified" or "412 Precondition Failed" instead.
+//
+// - Range and If-Range are honored for "200 OK" responses to GET and HEAD
+//   requests, if the Body has a known length and provides io.ReaderAt.  Such
+//   responses also advertise "Accept-Ranges: bytes".
+//
+// Whenever the Body is not sent, it is closed.
+//
+func (resp *Response) ServeRequest(w http.ResponseWriter, req *http.Request) error {
+	assert.NotNil(&req)
+
+	code, hdrs, b := resp.code, resp.hdrs, resp.body
+
+	if len(resp.variants) != 0 {
+		hdrs, b = resp.selectVariant(req, hdrs, b)
+	}
+
+	if code >= 200 && code <= 299 {
+		code, hdrs, b = resp.applyPreconditions(req, code, hdrs, b)
+	}
+
+	if code == http.StatusOK && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+		code, hdrs, b = resp.applyRange(req, hdrs, b)
+	}
+
+	return serveImpl(w, code, hdrs, b)
+}
+
+func serveImpl(w http.ResponseWriter, code int, hdrs http.Header, b body.Body) error {
 	if x, ok := w.(http.Pusher); ok {
-		vlist := resp.hdrs[headerPush]
+		vlist := hdrs[headerPush]
 		for _, url := range vlist {
 			err := x.Push(url, &http.PushOptions{})
 			if err != nil && !errors.Is(err, http.ErrNotSupported) {
@@ -86,17 +206,17 @@ func (resp *Response) Serve(w http.ResponseWriter) error {
 	}
 
 	h := w.Header()
-	for k, vlist := range resp.hdrs {
+	for k, vlist := range hdrs {
 		if k != headerPush {
 			h[k] = vlist
 		}
 	}
 
-	w.WriteHeader(resp.code)
+	w.WriteHeader(code)
 
-	_, err := io.Copy(w, resp.body)
+	_, err := io.Copy(w, b)
 
-	err2 := resp.body.Close()
+	err2 := b.Close()
 	if err == nil {
 		err = err2
 	}
@@ -0,0 +1,101 @@
+//go:build linux
+// +build linux
+
+package response
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chronos-tachyon/morehttp/body"
+)
+
+// hiddenWriterTo hides a Body's io.WriterTo, io.ReaderAt, and io.Seeker
+// implementations, forcing the userspace copy that a Body over an *os.File
+// used to get.
+type hiddenWriterTo struct {
+	body.Body
+}
+
+func benchmarkServeFile(b *testing.B, hide bool, useRange bool) {
+	const size = 16 << 20
+
+	path := filepath.Join(b.TempDir(), "input.bin")
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	if err := os.WriteFile(path, data, 0666); err != nil {
+		b.Fatalf("WriteFile failed: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		f, err := os.Open(path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		bb, err := body.FromReader(f)
+		if err != nil {
+			_ = f.Close()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if useRange {
+			sliced, err := body.Slice(bb, 4096, size-8192)
+			_ = bb.Close()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			bb = sliced
+		}
+		if hide {
+			bb = hiddenWriterTo{bb}
+		}
+
+		resp := NewBuilder().WithContentType("application/octet-stream").WithBody(bb).Build()
+		_ = resp.ServeRequest(NewWriter(w, req), req)
+	}))
+	defer srv.Close()
+
+	client := srv.Client()
+	buf := make([]byte, 256<<10)
+
+	b.ReportAllocs()
+	b.SetBytes(size)
+	b.ResetTimer()
+
+	for iter := 0; iter < b.N; iter++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			b.Fatalf("Get failed: %v", err)
+		}
+		_, err = io.CopyBuffer(io.Discard, resp.Body, buf)
+		_ = resp.Body.Close()
+		if err != nil {
+			b.Fatalf("Copy failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkServeFile_Userspace(b *testing.B) {
+	benchmarkServeFile(b, true, false)
+}
+
+func BenchmarkServeFile_SendFile(b *testing.B) {
+	benchmarkServeFile(b, false, false)
+}
+
+func BenchmarkServeFile_SliceUserspace(b *testing.B) {
+	benchmarkServeFile(b, true, true)
+}
+
+func BenchmarkServeFile_SliceSendFile(b *testing.B) {
+	benchmarkServeFile(b, false, true)
+}
@@ -0,0 +1,101 @@
+package response
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chronos-tachyon/morehttp/body"
+	"github.com/chronos-tachyon/morehttp/internal/negotiate"
+)
+
+const identityEncoding = "identity"
+
+func copyVariants(src map[string]body.Body) (map[string]body.Body, error) {
+	if len(src) == 0 {
+		return nil, nil
+	}
+
+	dst := make(map[string]body.Body, len(src))
+	for name, b := range src {
+		dupe, err := b.Copy()
+		if err != nil {
+			closeVariants(dst)
+			return nil, err
+		}
+		dst[name] = dupe
+	}
+	return dst, nil
+}
+
+func closeVariants(variants map[string]body.Body) {
+	for _, b := range variants {
+		_ = b.Close()
+	}
+}
+
+// variantNames returns the names of the available encoded variants in order
+// of server preference: shortest known length first, then by name.
+func variantNames(variants map[string]body.Body) []string {
+	type item struct {
+		name   string
+		length int64
+	}
+
+	items := make([]item, 0, len(variants))
+	for name, b := range variants {
+		items = append(items, item{name, b.BytesRemaining()})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if (a.length < 0) != (b.length < 0) {
+			return b.length < 0
+		}
+		if a.length != b.length {
+			return a.length < b.length
+		}
+		return a.name < b.name
+	})
+
+	names := make([]string, len(items), len(items)+1)
+	for index, item := range items {
+		names[index] = item.name
+	}
+	return names
+}
+
+func (resp *Response) selectVariant(req *http.Request, hdrs http.Header, b body.Body) (http.Header, body.Body) {
+	variants := resp.variants
+	resp.variants = nil
+
+	hdrs = copyHeaders(hdrs)
+	if hdrs == nil {
+		hdrs = make(http.Header, 16)
+	}
+	negotiate.AddVary(hdrs, "Accept-Encoding")
+
+	available := append(variantNames(variants), identityEncoding)
+	name, ok := negotiate.SelectEncoding(req.Header.Values("Accept-Encoding"), available)
+	if !ok || name == identityEncoding {
+		closeVariants(variants)
+		return hdrs, b
+	}
+
+	chosen := variants[name]
+	delete(variants, name)
+	closeVariants(variants)
+	_ = b.Close()
+
+	hdrs.Set("Content-Encoding", name)
+	if length := chosen.BytesRemaining(); length >= 0 {
+		hdrs.Set("Content-Length", strconv.FormatInt(length, 10))
+	} else {
+		hdrs.Del("Content-Length")
+	}
+	if etag := hdrs.Get("Etag"); strings.HasSuffix(etag, `"`) {
+		hdrs.Set("Etag", etag[:len(etag)-1]+"-"+name+`"`)
+	}
+	return hdrs, chosen
+}
@@ -0,0 +1,131 @@
+package response
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chronos-tachyon/morehttp/body"
+	"github.com/chronos-tachyon/morehttp/internal/mockreader"
+)
+
+func TestServeRequest_EncodedVariants(t *testing.T) {
+	type testRow struct {
+		AcceptEncoding string
+		Encoding       string
+		ETag           string
+		Data           string
+	}
+
+	testData := []testRow{
+		{"", "", `"v1"`, "plain text"},
+		{"gzip", "gzip", `"v1-gzip"`, "GZ"},
+		{"gzip, br", "br", `"v1-br"`, "B"},
+		{"gzip;q=1, br;q=0.5", "gzip", `"v1-gzip"`, "GZ"},
+		{"zstd", "", `"v1"`, "plain text"},
+		{"*;q=0.1, identity", "", `"v1"`, "plain text"},
+	}
+
+	for _, row := range testData {
+		identity := body.FromString("plain text")
+		gz := body.FromString("GZ")
+		br := body.FromString("B")
+
+		resp := NewBuilder().
+			WithContentType("text/plain").
+			WithETag("v1", true).
+			WithBody(identity).
+			WithEncodedVariants(map[string]body.Body{"gzip": gz, "br": br}).
+			Build()
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if row.AcceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", row.AcceptEncoding)
+		}
+
+		result := serveForTest(t, resp, req)
+		if expect, actual := row.Encoding, result.Header.Get("Content-Encoding"); expect != actual {
+			t.Errorf("%q: expected Content-Encoding %q, got %q", row.AcceptEncoding, expect, actual)
+		}
+		if expect, actual := "Accept-Encoding", result.Header.Get("Vary"); expect != actual {
+			t.Errorf("%q: expected Vary %q, got %q", row.AcceptEncoding, expect, actual)
+		}
+		if expect, actual := row.ETag, result.Header.Get("Etag"); expect != actual {
+			t.Errorf("%q: expected ETag %q, got %q", row.AcceptEncoding, expect, actual)
+		}
+		data := readAllForTest(t, result.Body)
+		if expect := row.Data; expect != data {
+			t.Errorf("%q: expected body %q, got %q", row.AcceptEncoding, expect, data)
+		}
+		if expect, actual := len(data), result.ContentLength; int64(expect) != actual {
+			t.Errorf("%q: expected Content-Length %d, got %d", row.AcceptEncoding, expect, actual)
+		}
+
+		for _, b := range []body.Body{identity, gz, br} {
+			if err := b.Close(); !errors.Is(err, fs.ErrClosed) {
+				t.Errorf("%q: expected all Bodies to be closed, got %v", row.AcceptEncoding, err)
+			}
+		}
+	}
+}
+
+func TestServe_EncodedVariants(t *testing.T) {
+	gz := body.FromString("GZ")
+	resp := NewBuilder().
+		WithBody(body.FromString("plain text")).
+		WithEncodedVariants(map[string]body.Body{"gzip": gz}).
+		Build()
+
+	w := httptest.NewRecorder()
+	if err := resp.Serve(w); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+	if expect, actual := "plain text", w.Body.String(); expect != actual {
+		t.Errorf("expected body %q, got %q", expect, actual)
+	}
+	if err := gz.Close(); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("expected variant to be closed, got %v", err)
+	}
+}
+
+func TestBuilder_ReplacedVariantsAreClosed(t *testing.T) {
+	type testRow struct {
+		Name    string
+		Replace func(*Builder)
+	}
+
+	testData := []testRow{
+		{"WithEncodedVariants", func(builder *Builder) {
+			builder.WithEncodedVariants(map[string]body.Body{"br": body.FromString("br")})
+		}},
+		{"ErrorPage", func(builder *Builder) {
+			builder.ErrorPage(http.StatusInternalServerError, errors.New("boom"))
+		}},
+		{"RedirectPage", func(builder *Builder) {
+			builder.RedirectPage(http.StatusFound, "/elsewhere")
+		}},
+	}
+
+	for _, row := range testData {
+		r := mockreader.New(
+			mockreader.ExpectClose(nil),
+			mockreader.ExpectMark("Replace-End"),
+		)
+		gz, err := body.FromReader(mockreader.Wrapper000{Inner: r})
+		if err != nil {
+			t.Fatalf("%s: FromReader failed: %v", row.Name, err)
+		}
+
+		builder := NewBuilder().
+			WithBody(body.FromString("content")).
+			WithEncodedVariants(map[string]body.Body{"gzip": gz})
+		row.Replace(builder)
+
+		// Panics if the replaced variant was not closed.
+		r.Mark("Replace-End")
+
+		_ = builder.Build().Close()
+	}
+}
@@ -0,0 +1,272 @@
+// Package router provides an HTTP request router whose routes are
+// handler.Handler values.
+package router
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chronos-tachyon/assert"
+
+	"github.com/chronos-tachyon/morehttp/body"
+	"github.com/chronos-tachyon/morehttp/handler"
+	"github.com/chronos-tachyon/morehttp/response"
+)
+
+// ErrNotFound is the error which is passed to the PageGenerator when no route
+// matches the request.
+var ErrNotFound = errors.New("no route matches the requested path")
+
+// ErrMethodNotAllowed is the error which is passed to the PageGenerator when
+// at least one route matches the requested path, but none of them accept the
+// request method.
+var ErrMethodNotAllowed = errors.New("the requested path does not support the request method")
+
+// Router dispatches requests to Handlers based on the request method, host,
+// and path.
+//
+// Routes are tried in the order in which they were added.  Requests for HEAD
+// are dispatched to GET routes if no HEAD route matches.  If some
+// routes match the path but none match the method, the Router replies with
+// "405 Method Not Allowed" and an Allow header; OPTIONS requests are answered
+// automatically in the same situation.  If no routes match the path, the
+// Router replies with "404 Not Found".
+//
+type Router struct {
+	mu     sync.RWMutex
+	gen    response.PageGenerator
+	routes []*Route
+}
+
+// New constructs an empty Router.
+func New() *Router {
+	return &Router{}
+}
+
+// WithPageGenerator sets the PageGenerator used for the Router's own error
+// pages.
+//
+// The given value MUST NOT be nil.
+//
+func (r *Router) WithPageGenerator(gen response.PageGenerator) *Router {
+	assert.NotNil(&gen)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.gen = gen
+	return r
+}
+
+// Add adds a route which dispatches requests matching the given method and
+// path template to the given Handler.  An empty method matches every method.
+//
+// A path template is a sequence of "/"-separated segments, each of which is
+// either literal text, a named parameter of the form "{name}" which matches
+// exactly one non-empty segment, or, as the last segment only, a wildcard of
+// the form "{name...}" which matches the remainder of the path.  The
+// remainder may be empty, so "/files/{path...}" matches "/files" and
+// "/files/" as well as "/files/a/b".
+//
+// Templates are matched against the escaped form of the request path, so an
+// escaped "/" (%2F) does not split a segment.  Parameter values are
+// unescaped.
+//
+// Panics if the path template is invalid.
+//
+func (r *Router) Add(method string, pattern string, h handler.Handler) *Route {
+	assert.NotNil(&h)
+
+	t, err := parseTemplate(pattern)
+	if err != nil {
+		panic(err)
+	}
+
+	route := &Route{
+		method:  strings.ToUpper(method),
+		tmpl:    t,
+		handler: h,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.routes = append(r.routes, route)
+	return route
+}
+
+// AddFunc is a convenience wrapper around Add.
+func (r *Router) AddFunc(method string, pattern string, fn func(*http.Request) response.Response) *Route {
+	return r.Add(method, pattern, handler.HandlerFunc(fn))
+}
+
+// Handle dispatches the request to the first matching route.
+func (r *Router) Handle(req *http.Request) response.Response {
+	r.mu.RLock()
+	gen := r.gen
+	routes := r.routes
+	r.mu.RUnlock()
+
+	host := requestHost(req)
+	path := req.URL.EscapedPath()
+
+	var (
+		fallback       *Route
+		fallbackParams map[string]string
+	)
+	methods := make(map[string]bool, 8)
+	for _, route := range routes {
+		if !route.matchHost(host) {
+			continue
+		}
+
+		params, ok := route.tmpl.match(path)
+		if !ok {
+			continue
+		}
+
+		if route.method == "" || route.method == req.Method {
+			return route.serve(req, params)
+		}
+
+		if req.Method == http.MethodHead && route.method == http.MethodGet && fallback == nil {
+			fallback = route
+			fallbackParams = params
+		}
+		methods[route.method] = true
+	}
+
+	if fallback != nil {
+		return fallback.serve(req, fallbackParams)
+	}
+
+	builder := response.NewBuilder()
+	if gen != nil {
+		builder.WithPageGenerator(gen)
+	}
+	builder.WithRequestID(handler.GetRequestID(req))
+
+	if len(methods) == 0 {
+		return *builder.ErrorPage(http.StatusNotFound, ErrNotFound).Build()
+	}
+
+	allow := allowHeader(methods)
+	if req.Method == http.MethodOptions {
+		builder.WithStatus(http.StatusNoContent)
+		builder.WithHeader("Allow", allow, false)
+		builder.WithBody(body.Empty())
+		return *builder.Build()
+	}
+
+	builder.ErrorPage(http.StatusMethodNotAllowed, ErrMethodNotAllowed)
+	builder.WithHeader("Allow", allow, false)
+	return *builder.Build()
+}
+
+var _ handler.Handler = (*Router)(nil)
+
+// Route is a single route within a Router.
+type Route struct {
+	mu      sync.Mutex
+	method  string
+	host    string
+	name    string
+	tmpl    template
+	handler handler.Handler
+}
+
+// Name sets the name of the route, which is reported to handler.SetRouteName
+// when the route matches.  If not set, the path template is used.
+func (route *Route) Name(name string) *Route {
+	route.mu.Lock()
+	defer route.mu.Unlock()
+
+	route.name = name
+	return route
+}
+
+// Host restricts the route to requests for the given host name.  A leading
+// "*." matches any single subdomain label.  The port, if any, is ignored.
+func (route *Route) Host(host string) *Route {
+	route.mu.Lock()
+	defer route.mu.Unlock()
+
+	route.host = strings.ToLower(host)
+	return route
+}
+
+func (route *Route) serve(req *http.Request, params map[string]string) response.Response {
+	handler.SetRouteName(req, route.routeName())
+	if params != nil {
+		ctx := context.WithValue(req.Context(), paramsKey{}, params)
+		req = req.WithContext(ctx)
+	}
+	return route.handler.Handle(req)
+}
+
+func (route *Route) routeName() string {
+	route.mu.Lock()
+	defer route.mu.Unlock()
+
+	if route.name == "" {
+		return route.tmpl.raw
+	}
+	return route.name
+}
+
+func (route *Route) matchHost(host string) bool {
+	route.mu.Lock()
+	pattern := route.host
+	route.mu.Unlock()
+
+	if pattern == "" {
+		return true
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		suffix := pattern[1:]
+		return strings.HasSuffix(host, suffix) && !strings.Contains(host[:len(host)-len(suffix)], ".") && len(host) > len(suffix)
+	}
+	return host == pattern
+}
+
+func requestHost(req *http.Request) string {
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+type paramsKey struct{}
+
+// Params returns the path parameters which were extracted by the Router that
+// dispatched the given request.  The caller MUST NOT modify the returned map.
+func Params(req *http.Request) map[string]string {
+	params, _ := req.Context().Value(paramsKey{}).(map[string]string)
+	return params
+}
+
+// Param returns the named path parameter, or the empty string if it is not
+// present.
+func Param(req *http.Request, name string) string {
+	return Params(req)[name]
+}
+
+func allowHeader(methods map[string]bool) string {
+	if methods[http.MethodGet] {
+		methods[http.MethodHead] = true
+	}
+	methods[http.MethodOptions] = true
+
+	list := make([]string, 0, len(methods))
+	for method := range methods {
+		list = append(list, method)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
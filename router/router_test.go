@@ -0,0 +1,148 @@
+package router
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/chronos-tachyon/morehttp/body"
+	"github.com/chronos-tachyon/morehttp/handler"
+	"github.com/chronos-tachyon/morehttp/response"
+)
+
+func TestTemplate(t *testing.T) {
+	type testRow struct {
+		Template string
+		Path     string
+		Params   map[string]string
+		OK       bool
+	}
+
+	testData := []testRow{
+		{"/", "/", nil, true},
+		{"/", "/x", nil, false},
+		{"/users", "/users", nil, true},
+		{"/users", "/users/", nil, false},
+		{"/users/{id}", "/users/42", map[string]string{"id": "42"}, true},
+		{"/users/{id}", "/users/", nil, false},
+		{"/users/{id}", "/users/42/x", nil, false},
+		{"/users/{id}/files/{path...}", "/users/42/files/a/b/c", map[string]string{"id": "42", "path": "a/b/c"}, true},
+		{"/users/{id}/files/{path...}", "/users/42/files/", map[string]string{"id": "42", "path": ""}, true},
+		{"/users/{id}/files/{path...}", "/users/42/files", map[string]string{"id": "42", "path": ""}, true},
+		{"/users/{id}/files/{path...}", "/users/42", nil, false},
+		{"/users/{id}", "/users/a%2Fb", map[string]string{"id": "a/b"}, true},
+		{"/users/{id}/files/{path...}", "/users/a%2Fb/files/c%20d/e", map[string]string{"id": "a/b", "path": "c d/e"}, true},
+		{"/users/{id}", "/users/%zz", nil, false},
+		{"/caf\u00e9", "/caf%C3%A9", nil, true},
+		{"/{path...}", "/anything/at/all", map[string]string{"path": "anything/at/all"}, true},
+	}
+
+	for _, row := range testData {
+		tmpl, err := parseTemplate(row.Template)
+		if err != nil {
+			t.Errorf("parseTemplate(%q) failed: %v", row.Template, err)
+			continue
+		}
+		params, ok := tmpl.match(row.Path)
+		if ok != row.OK || !reflect.DeepEqual(params, row.Params) {
+			t.Errorf("%q.match(%q): expected (%v, %v), got (%v, %v)", row.Template, row.Path, row.Params, row.OK, params, ok)
+		}
+	}
+
+	for _, bad := range []string{"users", "/{a}/{a}", "/{path...}/x", "/x{id}", "/{}"} {
+		if _, err := parseTemplate(bad); err == nil {
+			t.Errorf("parseTemplate(%q): expected error", bad)
+		}
+	}
+}
+
+func textHandler(text string) func(*http.Request) response.Response {
+	return func(req *http.Request) response.Response {
+		return *response.NewBuilder().
+			WithContentType("text/plain").
+			WithBody(body.FromString(text + " " + Param(req, "id") + " " + handler.RouteName(req))).
+			Build()
+	}
+}
+
+func TestRouter(t *testing.T) {
+	r := New()
+	r.AddFunc(http.MethodGet, "/users/{id}", textHandler("get-user")).Name("user")
+	r.AddFunc(http.MethodDelete, "/users/{id}", textHandler("delete-user"))
+	r.AddFunc("", "/any", textHandler("any"))
+	r.AddFunc(http.MethodGet, "/host", textHandler("host")).Host("*.example.com")
+
+	a := handler.Adaptor{Inner: r}
+
+	type testRow struct {
+		Method string
+		Target string
+		Status int
+		Allow  string
+		Body   string
+	}
+
+	testData := []testRow{
+		{http.MethodGet, "/users/42", http.StatusOK, "", "get-user 42 user"},
+		{http.MethodGet, "/users/a%2Fb", http.StatusOK, "", "get-user a/b user"},
+		{http.MethodDelete, "/users/42", http.StatusOK, "", "delete-user 42 /users/{id}"},
+		{http.MethodHead, "/users/42", http.StatusOK, "", ""},
+		{http.MethodPost, "/users/42", http.StatusMethodNotAllowed, "DELETE, GET, HEAD, OPTIONS", ""},
+		{http.MethodOptions, "/users/42", http.StatusNoContent, "DELETE, GET, HEAD, OPTIONS", ""},
+		{http.MethodPatch, "/any", http.StatusOK, "", "any  /any"},
+		{http.MethodGet, "/nope", http.StatusNotFound, "", ""},
+		{http.MethodGet, "http://www.example.com/host", http.StatusOK, "", "host  /host"},
+		{http.MethodGet, "http://example.com/host", http.StatusNotFound, "", ""},
+		{http.MethodGet, "http://a.b.example.com/host", http.StatusNotFound, "", ""},
+	}
+
+	for _, row := range testData {
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, httptest.NewRequest(row.Method, row.Target, nil))
+		result := w.Result()
+
+		if result.StatusCode != row.Status {
+			t.Errorf("%s %s: expected status %d, got %d", row.Method, row.Target, row.Status, result.StatusCode)
+			continue
+		}
+		if expect, actual := row.Allow, result.Header.Get("Allow"); expect != actual {
+			t.Errorf("%s %s: expected Allow %q, got %q", row.Method, row.Target, expect, actual)
+		}
+		if row.Status == http.StatusOK && row.Method != http.MethodHead {
+			raw, _ := io.ReadAll(result.Body)
+			if expect, actual := row.Body, string(raw); expect != actual {
+				t.Errorf("%s %s: expected body %q, got %q", row.Method, row.Target, expect, actual)
+			}
+		}
+	}
+}
+
+func TestRouter_RequestID(t *testing.T) {
+	r := New()
+	r.AddFunc(http.MethodGet, "/users/{id}", textHandler("get-user"))
+
+	a := handler.Adaptor{Inner: r, RequestID: &handler.RequestIDOptions{}}
+
+	type testRow struct {
+		Method string
+		Target string
+		Expect string
+	}
+
+	testData := []testRow{
+		{http.MethodGet, "/nope", "404 Not Found\r\nRequest ID: req-1\r\n"},
+		{http.MethodPost, "/users/42", "405 Method Not Allowed\r\nRequest ID: req-1\r\n"},
+	}
+
+	for _, row := range testData {
+		req := httptest.NewRequest(row.Method, row.Target, nil)
+		req.Header.Set(handler.DefaultRequestIDHeader, "req-1")
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, req)
+		if actual := w.Body.String(); row.Expect != actual {
+			t.Errorf("%s %s: expected body %q, got %q", row.Method, row.Target, row.Expect, actual)
+		}
+	}
+}
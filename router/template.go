@@ -0,0 +1,126 @@
+package router
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type segmentKind uint8
+
+const (
+	literalSegment segmentKind = iota
+	paramSegment
+	wildcardSegment
+)
+
+type segment struct {
+	kind  segmentKind
+	value string
+}
+
+// template is a parsed path template, such as "/users/{id}/files/{path...}".
+type template struct {
+	raw      string
+	segments []segment
+}
+
+func parseTemplate(raw string) (template, error) {
+	if !strings.HasPrefix(raw, "/") {
+		return template{}, fmt.Errorf("path template %q must begin with '/'", raw)
+	}
+
+	pieces := strings.Split(raw[1:], "/")
+	segments := make([]segment, len(pieces))
+	seen := make(map[string]bool, len(pieces))
+	for index, piece := range pieces {
+		if !strings.HasPrefix(piece, "{") || !strings.HasSuffix(piece, "}") {
+			if strings.ContainsAny(piece, "{}") {
+				return template{}, fmt.Errorf("path template %q: segment %q mixes literal text and parameters", raw, piece)
+			}
+			segments[index] = segment{kind: literalSegment, value: piece}
+			continue
+		}
+
+		name := piece[1 : len(piece)-1]
+		kind := paramSegment
+		if strings.HasSuffix(name, "...") {
+			name = name[:len(name)-3]
+			kind = wildcardSegment
+			if index != len(pieces)-1 {
+				return template{}, fmt.Errorf("path template %q: wildcard {%s...} must be the last segment", raw, name)
+			}
+		}
+		if name == "" || strings.ContainsAny(name, "{}./") {
+			return template{}, fmt.Errorf("path template %q: invalid parameter name %q", raw, name)
+		}
+		if seen[name] {
+			return template{}, fmt.Errorf("path template %q: duplicate parameter name %q", raw, name)
+		}
+		seen[name] = true
+		segments[index] = segment{kind: kind, value: name}
+	}
+
+	return template{raw: raw, segments: segments}, nil
+}
+
+// match reports whether the given escaped path matches this template,
+// returning the unescaped values of the named parameters if so.
+func (t template) match(path string) (map[string]string, bool) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+
+	var params map[string]string
+	setParam := func(name, value string) {
+		if params == nil {
+			params = make(map[string]string, len(t.segments))
+		}
+		params[name] = value
+	}
+
+	rest := path[1:]
+	for index, seg := range t.segments {
+		if seg.kind == wildcardSegment {
+			value, err := url.PathUnescape(rest)
+			if err != nil {
+				return nil, false
+			}
+			setParam(seg.value, value)
+			return params, true
+		}
+
+		var piece string
+		last := (index == len(t.segments)-1)
+		beforeWildcard := (index == len(t.segments)-2 && t.segments[index+1].kind == wildcardSegment)
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			if last {
+				return nil, false
+			}
+			piece, rest = rest[:i], rest[i+1:]
+		} else {
+			if !last && !beforeWildcard {
+				return nil, false
+			}
+			piece, rest = rest, ""
+		}
+
+		piece, err := url.PathUnescape(piece)
+		if err != nil {
+			return nil, false
+		}
+
+		switch seg.kind {
+		case literalSegment:
+			if piece != seg.value {
+				return nil, false
+			}
+		case paramSegment:
+			if piece == "" {
+				return nil, false
+			}
+			setParam(seg.value, piece)
+		}
+	}
+	return params, true
+}
@@ -0,0 +1,184 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// MemoryTracer is a Tracer which keeps finished spans in memory.  It is
+// intended for tests.
+type MemoryTracer struct {
+	mu    sync.Mutex
+	spans []*MemorySpan
+}
+
+// NewMemoryTracer constructs an empty MemoryTracer.
+func NewMemoryTracer() *MemoryTracer {
+	return &MemoryTracer{}
+}
+
+// Start begins a new MemorySpan.
+func (tracer *MemoryTracer) Start(ctx context.Context, name string, parent SpanContext) Span {
+	sc := SpanContext{Flags: FlagSampled}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Flags = parent.Flags
+		sc.TraceState = parent.TraceState
+	} else {
+		randomBytes(sc.TraceID[:])
+	}
+	randomBytes(sc.SpanID[:])
+
+	return &MemorySpan{
+		tracer:     tracer,
+		name:       name,
+		sc:         sc,
+		parent:     parent,
+		startTime:  time.Now(),
+		attributes: make(map[string]interface{}, 8),
+	}
+}
+
+// Spans returns the spans which have ended, in the order that they ended.
+func (tracer *MemoryTracer) Spans() []*MemorySpan {
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+
+	out := make([]*MemorySpan, len(tracer.spans))
+	copy(out, tracer.spans)
+	return out
+}
+
+// Reset discards all ended spans.
+func (tracer *MemoryTracer) Reset() {
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+
+	tracer.spans = nil
+}
+
+// MemorySpan is the Span implementation for MemoryTracer.
+type MemorySpan struct {
+	tracer *MemoryTracer
+
+	mu         sync.Mutex
+	name       string
+	sc         SpanContext
+	parent     SpanContext
+	startTime  time.Time
+	endTime    time.Time
+	attributes map[string]interface{}
+	errs       []error
+	status     StatusCode
+	statusDesc string
+	ended      bool
+}
+
+func (span *MemorySpan) SpanContext() SpanContext {
+	return span.sc
+}
+
+func (span *MemorySpan) SetName(name string) {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	span.name = name
+}
+
+func (span *MemorySpan) SetAttribute(key string, value interface{}) {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	span.attributes[key] = value
+}
+
+func (span *MemorySpan) RecordError(err error) {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	span.errs = append(span.errs, err)
+}
+
+func (span *MemorySpan) SetStatus(code StatusCode, description string) {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	span.status = code
+	span.statusDesc = description
+}
+
+func (span *MemorySpan) End() {
+	span.mu.Lock()
+	if span.ended {
+		span.mu.Unlock()
+		return
+	}
+	span.ended = true
+	span.endTime = time.Now()
+	span.mu.Unlock()
+
+	span.tracer.mu.Lock()
+	span.tracer.spans = append(span.tracer.spans, span)
+	span.tracer.mu.Unlock()
+}
+
+// Name returns the span's name.
+func (span *MemorySpan) Name() string {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	return span.name
+}
+
+// Parent returns the SpanContext of the span's parent, if any.
+func (span *MemorySpan) Parent() SpanContext {
+	return span.parent
+}
+
+// Attribute returns the value of the named attribute.
+func (span *MemorySpan) Attribute(key string) (interface{}, bool) {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	value, found := span.attributes[key]
+	return value, found
+}
+
+// Errors returns the errors recorded by RecordError.
+func (span *MemorySpan) Errors() []error {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	out := make([]error, len(span.errs))
+	copy(out, span.errs)
+	return out
+}
+
+// Status returns the status set by SetStatus.
+func (span *MemorySpan) Status() (StatusCode, string) {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	return span.status, span.statusDesc
+}
+
+// Duration returns the time between the span's start and end.
+func (span *MemorySpan) Duration() time.Duration {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	return span.endTime.Sub(span.startTime)
+}
+
+func randomBytes(p []byte) {
+	if _, err := rand.Read(p); err != nil {
+		panic(err)
+	}
+}
+
+var (
+	_ Tracer = (*MemoryTracer)(nil)
+	_ Span   = (*MemorySpan)(nil)
+)
@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemoryTracer(t *testing.T) {
+	tracer := NewMemoryTracer()
+
+	root := tracer.Start(context.Background(), "root", SpanContext{})
+	if !root.SpanContext().IsValid() {
+		t.Errorf("expected valid SpanContext for root span")
+	}
+
+	ctx := ContextWithSpan(context.Background(), root)
+	if SpanFromContext(ctx) != root {
+		t.Errorf("SpanFromContext: expected root span")
+	}
+
+	child := tracer.Start(ctx, "child", SpanContextFromContext(ctx))
+	if expect, actual := root.SpanContext().TraceID, child.SpanContext().TraceID; expect != actual {
+		t.Errorf("expected child TraceID %v, got %v", expect, actual)
+	}
+	if root.SpanContext().SpanID == child.SpanContext().SpanID {
+		t.Errorf("expected child to have a distinct SpanID")
+	}
+
+	errTest := errors.New("oops")
+	child.SetAttribute("key", "value")
+	child.RecordError(errTest)
+	child.SetStatus(StatusError, "oops")
+	child.End()
+	root.End()
+	root.End()
+
+	spans := tracer.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if expect, actual := "child", spans[0].Name(); expect != actual {
+		t.Errorf("expected name %q, got %q", expect, actual)
+	}
+	if expect, actual := root.SpanContext(), spans[0].Parent(); expect != actual {
+		t.Errorf("expected parent %v, got %v", expect, actual)
+	}
+	if value, _ := spans[0].Attribute("key"); value != "value" {
+		t.Errorf("expected attribute %q, got %v", "value", value)
+	}
+	if errs := spans[0].Errors(); len(errs) != 1 || errs[0] != errTest {
+		t.Errorf("expected errors [%v], got %v", errTest, errs)
+	}
+	if code, _ := spans[0].Status(); code != StatusError {
+		t.Errorf("expected StatusError, got %v", code)
+	}
+
+	tracer.Reset()
+	if n := len(tracer.Spans()); n != 0 {
+		t.Errorf("expected 0 spans after Reset, got %d", n)
+	}
+}
+
+func TestSpanFromContext_Empty(t *testing.T) {
+	if span := SpanFromContext(context.Background()); span != nil {
+		t.Errorf("expected nil, got %v", span)
+	}
+	if sc := SpanContextFromContext(context.Background()); sc.IsValid() {
+		t.Errorf("expected invalid SpanContext, got %v", sc)
+	}
+}
@@ -0,0 +1,119 @@
+// Package tracing defines a minimal distributed tracing interface, plus a
+// W3C Trace Context implementation, so that morehttp can produce spans without
+// depending on any particular tracing SDK.
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+)
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// IsValid reports whether the TraceID is non-zero.
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// String returns the TraceID as 32 lowercase hexadecimal digits.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// IsValid reports whether the SpanID is non-zero.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// String returns the SpanID as 16 lowercase hexadecimal digits.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// FlagSampled is the trace flag which indicates that the caller may have
+// recorded the trace.
+const FlagSampled byte = 0x01
+
+// SpanContext is the portion of a span's identity which is propagated across
+// process boundaries.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+	Remote     bool
+}
+
+// IsValid reports whether both the TraceID and the SpanID are non-zero.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// IsSampled reports whether FlagSampled is set.
+func (sc SpanContext) IsSampled() bool {
+	return (sc.Flags & FlagSampled) != 0
+}
+
+// StatusCode is the status of a finished span.
+type StatusCode uint8
+
+const (
+	StatusUnset StatusCode = iota
+	StatusOK
+	StatusError
+)
+
+// Span is a single operation within a trace.
+type Span interface {
+	// SpanContext returns the span's identity.
+	SpanContext() SpanContext
+
+	// SetName replaces the span's name.
+	SetName(name string)
+
+	// SetAttribute records a key-value attribute on the span.  The value
+	// is a string, bool, int64, or float64.
+	SetAttribute(key string, value interface{})
+
+	// RecordError records an error which occurred during the span.
+	RecordError(err error)
+
+	// SetStatus sets the span's final status.
+	SetStatus(code StatusCode, description string)
+
+	// End finishes the span.  No other methods may be called afterward.
+	End()
+}
+
+// Tracer creates spans.
+type Tracer interface {
+	// Start begins a new server span.  If parent is valid, the new span
+	// belongs to the parent's trace; otherwise, a new trace is started.
+	Start(ctx context.Context, name string, parent SpanContext) Span
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx which carries the given Span.
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the Span carried by ctx, or nil if none.
+func SpanFromContext(ctx context.Context) Span {
+	span, _ := ctx.Value(spanKey{}).(Span)
+	return span
+}
+
+// SpanContextFromContext returns the SpanContext of the Span carried by ctx,
+// or the zero SpanContext if none.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	return SpanContext{}
+}
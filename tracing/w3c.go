@@ -0,0 +1,164 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Header names defined by W3C Trace Context.
+const (
+	TraceparentHeader = "Traceparent"
+	TracestateHeader  = "Tracestate"
+)
+
+// ErrMalformedTraceparent is returned by ParseTraceparent when its input is
+// not a valid traceparent header value.
+var ErrMalformedTraceparent = errors.New("malformed traceparent header")
+
+const maxTracestateMembers = 32
+
+// ParseTraceparent parses a W3C traceparent header value, of the form
+// "00-<32 hex digit trace ID>-<16 hex digit span ID>-<2 hex digit flags>".
+//
+// Versions other than "00" are accepted if they follow the version 00 format,
+// possibly with additional "-"-separated fields, as the specification
+// requires.
+//
+func ParseTraceparent(str string) (SpanContext, error) {
+	str = strings.TrimSpace(str)
+	if len(str) < 55 {
+		return SpanContext{}, ErrMalformedTraceparent
+	}
+
+	version, ok := parseHexByte(str[0:2])
+	if !ok || version == 0xff {
+		return SpanContext{}, ErrMalformedTraceparent
+	}
+	if version == 0 && len(str) != 55 {
+		return SpanContext{}, ErrMalformedTraceparent
+	}
+	if len(str) > 55 && str[55] != '-' {
+		return SpanContext{}, ErrMalformedTraceparent
+	}
+	if str[2] != '-' || str[35] != '-' || str[52] != '-' {
+		return SpanContext{}, ErrMalformedTraceparent
+	}
+
+	var sc SpanContext
+	if !decodeLowerHex(sc.TraceID[:], str[3:35]) || !decodeLowerHex(sc.SpanID[:], str[36:52]) {
+		return SpanContext{}, ErrMalformedTraceparent
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, ErrMalformedTraceparent
+	}
+
+	flags, ok := parseHexByte(str[53:55])
+	if !ok {
+		return SpanContext{}, ErrMalformedTraceparent
+	}
+	sc.Flags = flags
+	sc.Remote = true
+	return sc, nil
+}
+
+// Traceparent formats the SpanContext as a version 00 traceparent header
+// value.
+func (sc SpanContext) Traceparent() string {
+	var buf strings.Builder
+	buf.Grow(55)
+	buf.WriteString("00-")
+	buf.WriteString(sc.TraceID.String())
+	buf.WriteByte('-')
+	buf.WriteString(sc.SpanID.String())
+	buf.WriteByte('-')
+	buf.WriteString(hex.EncodeToString([]byte{sc.Flags}))
+	return buf.String()
+}
+
+// Extract reads the traceparent and tracestate headers.  Returns false if
+// traceparent is absent or invalid, in which case tracestate is ignored too.
+func Extract(h http.Header) (SpanContext, bool) {
+	values := h.Values(TraceparentHeader)
+	if len(values) != 1 {
+		return SpanContext{}, false
+	}
+
+	sc, err := ParseTraceparent(values[0])
+	if err != nil {
+		return SpanContext{}, false
+	}
+
+	sc.TraceState = normalizeTracestate(h.Values(TracestateHeader))
+	return sc, true
+}
+
+// Inject writes the traceparent and tracestate headers for the given
+// SpanContext.  Does nothing if the SpanContext is not valid.
+func Inject(h http.Header, sc SpanContext) {
+	if !sc.IsValid() {
+		return
+	}
+	h.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		h.Set(TracestateHeader, sc.TraceState)
+	} else {
+		h.Del(TracestateHeader)
+	}
+}
+
+// normalizeTracestate combines multiple tracestate header values, dropping
+// empty or malformed list members.  Returns the empty string if the header
+// exceeds the limit on list members, as the specification permits.
+func normalizeTracestate(values []string) string {
+	var members []string
+	for _, value := range values {
+		for _, member := range strings.Split(value, ",") {
+			member = strings.TrimSpace(member)
+			if member == "" {
+				continue
+			}
+			i := strings.IndexByte(member, '=')
+			if i <= 0 || i == len(member)-1 {
+				continue
+			}
+			members = append(members, member)
+		}
+	}
+	if len(members) > maxTracestateMembers {
+		return ""
+	}
+	return strings.Join(members, ",")
+}
+
+func decodeLowerHex(dst []byte, src string) bool {
+	if len(src) != 2*len(dst) {
+		return false
+	}
+	for i := range dst {
+		b, ok := parseHexByte(src[2*i : 2*i+2])
+		if !ok {
+			return false
+		}
+		dst[i] = b
+	}
+	return true
+}
+
+func parseHexByte(str string) (byte, bool) {
+	hi, ok1 := hexDigit(str[0])
+	lo, ok2 := hexDigit(str[1])
+	return (hi << 4) | lo, ok1 && ok2
+}
+
+func hexDigit(ch byte) (byte, bool) {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return ch - '0', true
+	case ch >= 'a' && ch <= 'f':
+		return ch - 'a' + 10, true
+	default:
+		return 0, false
+	}
+}
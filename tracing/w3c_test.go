@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	type testRow struct {
+		Input string
+		OK    bool
+	}
+
+	testData := []testRow{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{" 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00 ", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz", false},
+		{"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false},
+		{"", false},
+	}
+
+	for _, row := range testData {
+		sc, err := ParseTraceparent(row.Input)
+		if row.OK != (err == nil) {
+			t.Errorf("ParseTraceparent(%q): expected ok=%v, got err=%v", row.Input, row.OK, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if expect, actual := "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String(); expect != actual {
+			t.Errorf("ParseTraceparent(%q): expected TraceID %q, got %q", row.Input, expect, actual)
+		}
+		if expect, actual := "00f067aa0ba902b7", sc.SpanID.String(); expect != actual {
+			t.Errorf("ParseTraceparent(%q): expected SpanID %q, got %q", row.Input, expect, actual)
+		}
+		if !sc.Remote {
+			t.Errorf("ParseTraceparent(%q): expected Remote", row.Input)
+		}
+	}
+}
+
+func TestTraceparent_RoundTrip(t *testing.T) {
+	const input = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(input)
+	if err != nil {
+		t.Fatalf("ParseTraceparent failed: %v", err)
+	}
+	if !sc.IsSampled() {
+		t.Errorf("expected IsSampled")
+	}
+	if actual := sc.Traceparent(); actual != input {
+		t.Errorf("expected %q, got %q", input, actual)
+	}
+}
+
+func TestExtractInject(t *testing.T) {
+	h := make(http.Header)
+	h.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.Add("Tracestate", "congo=t61rcWkgMzE, bogus")
+	h.Add("Tracestate", " rojo=00f067aa0ba902b7,,")
+
+	sc, ok := Extract(h)
+	if !ok {
+		t.Fatalf("Extract failed")
+	}
+	if expect, actual := "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7", sc.TraceState; expect != actual {
+		t.Errorf("expected TraceState %q, got %q", expect, actual)
+	}
+
+	out := make(http.Header)
+	Inject(out, sc)
+	if expect, actual := h.Get("Traceparent"), out.Get("Traceparent"); expect != actual {
+		t.Errorf("expected traceparent %q, got %q", expect, actual)
+	}
+	if expect, actual := sc.TraceState, out.Get("Tracestate"); expect != actual {
+		t.Errorf("expected tracestate %q, got %q", expect, actual)
+	}
+
+	h.Add("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if _, ok := Extract(h); ok {
+		t.Errorf("Extract: expected failure with duplicate traceparent")
+	}
+
+	empty := make(http.Header)
+	Inject(empty, SpanContext{})
+	if len(empty) != 0 {
+		t.Errorf("Inject: expected no headers for invalid SpanContext, got %v", empty)
+	}
+}